@@ -4,5 +4,5 @@ import (
 	"embed"
 )
 
-//go:embed "emails"
+//go:embed "emails" "locales"
 var EmbeddedFiles embed.FS
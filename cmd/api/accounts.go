@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// purgeDeletedUsers permanently removes accounts whose deletion grace period
+// has run out. Related rows are removed by ON DELETE CASCADE.
+func (app *application) purgeDeletedUsers(ctx context.Context) error {
+	deletedBefore := time.Now().Add(-app.config.accountDeletion.gracePeriod)
+
+	purged, err := app.db.PurgeDeletedUsers(ctx, deletedBefore)
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		app.logger.Info("purged deleted users", "count", purged)
+	}
+
+	return nil
+}
+
+func (app *application) runDeletedUsersPurge(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := app.purgeDeletedUsers(ctx)
+		cancel()
+
+		if err != nil {
+			app.logger.Error("failed to purge deleted users", "error", err)
+		}
+	}
+}
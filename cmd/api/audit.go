@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+
+	"github.com/google/uuid"
+	"github.com/tomasen/realip"
+)
+
+const (
+	auditLogin                    = "login"
+	auditPasswordChanged          = "password_changed"
+	auditPasswordReset            = "password_reset"
+	auditEmailChanged             = "email_changed"
+	auditAccountDeletionRequested = "account_deletion_requested"
+	auditAccountDeletionCancelled = "account_deletion_cancelled"
+	auditAccountLocked            = "account_locked"
+	auditAccountUnlocked          = "account_unlocked"
+	auditTOTPEnabled              = "totp_enabled"
+	auditTOTPDisabled             = "totp_disabled"
+)
+
+// recordAuditEvent stores a security-relevant action taken on the user's
+// account, together with the IP address the request came from.
+func recordAuditEvent(r *http.Request, db *database.Queries, userID uuid.UUID, action string) error {
+	return db.CreateAuditEvent(r.Context(), database.CreateAuditEventParams{
+		UserID:    userID,
+		Action:    action,
+		IpAddress: realip.FromRequest(r),
+	})
+}
@@ -5,12 +5,16 @@ import (
 	"net/http"
 
 	"github.com/jcarloasilo/golang-rest-template/internal/database"
+
+	"github.com/pascaldekloe/jwt"
 )
 
 type contextKey string
 
 const (
-	authenticatedUserContextKey = contextKey("authenticatedUser")
+	authenticatedUserContextKey    = contextKey("authenticatedUser")
+	authenticationClaimsContextKey = contextKey("authenticationClaims")
+	requestIDContextKey            = contextKey("requestID")
 )
 
 func contextSetAuthenticatedUser(r *http.Request, user *database.User) *http.Request {
@@ -26,3 +30,31 @@ func contextGetAuthenticatedUser(r *http.Request) *database.User {
 
 	return user
 }
+
+func contextSetAuthenticationClaims(r *http.Request, claims *jwt.Claims) *http.Request {
+	ctx := context.WithValue(r.Context(), authenticationClaimsContextKey, claims)
+	return r.WithContext(ctx)
+}
+
+func contextGetAuthenticationClaims(r *http.Request) *jwt.Claims {
+	claims, ok := r.Context().Value(authenticationClaimsContextKey).(*jwt.Claims)
+	if !ok {
+		return nil
+	}
+
+	return claims
+}
+
+func contextSetRequestID(r *http.Request, requestID string) *http.Request {
+	ctx := context.WithValue(r.Context(), requestIDContextKey, requestID)
+	return r.WithContext(ctx)
+}
+
+func contextGetRequestID(r *http.Request) string {
+	requestID, ok := r.Context().Value(requestIDContextKey).(string)
+	if !ok {
+		return ""
+	}
+
+	return requestID
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
 	"runtime/debug"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jcarloasilo/golang-rest-template/internal/response"
 	"github.com/jcarloasilo/golang-rest-template/internal/validator"
@@ -13,8 +17,31 @@ import (
 
 type ErrorMessage struct {
 	Message string `json:"error"`
+	Code    string `json:"code"`
 }
 
+// clientError is an error whose message is safe to show to the client,
+// tagged with a stable code.
+type clientError struct {
+	code    string
+	message string
+}
+
+func (e clientError) Error() string {
+	return e.message
+}
+
+var (
+	errOTPExpired           = clientError{"otp.expired", "expired otp"}
+	errOTPTooManyAttempts   = clientError{"otp.too_many_attempts", "too many failed attempts"}
+	errTOTPAlreadyEnabled   = clientError{"totp.already_enabled", "totp is already enabled"}
+	errTOTPNotEnabled       = clientError{"totp.not_enabled", "totp is not enabled"}
+	errNoEmailChangePending = clientError{"email_change.not_pending", "no email change is pending"}
+	errUserAlreadyVerified  = clientError{"user.already_verified", "user is already verified"}
+	errCannotDisableSelf    = clientError{"user.cannot_disable_self", "you cannot disable your own account"}
+	errCannotDeleteSelf     = clientError{"user.cannot_delete_self", "you cannot delete your own account"}
+)
+
 func (app *application) reportServerError(r *http.Request, err error) {
 	var (
 		message = err.Error()
@@ -23,43 +50,97 @@ func (app *application) reportServerError(r *http.Request, err error) {
 		trace   = string(debug.Stack())
 	)
 
-	requestAttrs := slog.Group("request", "method", method, "url", url)
+	requestAttrs := slog.Group("request", "id", contextGetRequestID(r), "method", method, "url", url)
 	app.logger.Error(message, requestAttrs, "trace", trace)
 }
 
-func (app *application) errorMessage(w http.ResponseWriter, r *http.Request, status int, message string, headers http.Header) {
+// errorMessage sends an error response, translating the message by its code
+// into the client's language. params are alternating key and value pairs for
+// the placeholders in the translated message, as in log/slog.
+func (app *application) errorMessage(w http.ResponseWriter, r *http.Request, status int, code, message string, headers http.Header, params ...any) {
 	message = strings.ToUpper(message[:1]) + message[1:]
+	message = app.localizer(r).Translate(code, message, validator.Params(params...))
+
+	w.Header().Add("Vary", "Accept-Language")
+
+	var err error
+	if app.config.problemDetails {
+		problem := app.newProblem(r, status, message)
+		problem.Code = code
+		err = response.ProblemJSON(w, problem, headers)
+	} else {
+		err = response.JSONWithHeaders(w, status, ErrorMessage{Message: message, Code: code}, headers)
+	}
 
-	err := response.JSONWithHeaders(w, status, map[string]string{"error": message}, headers)
 	if err != nil {
 		app.reportServerError(r, err)
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
 
+// newProblem builds an RFC 9457 problem details object for the request.
+func (app *application) newProblem(r *http.Request, status int, detail string) response.Problem {
+	problem := response.NewProblem(status, detail)
+	problem.Instance = r.URL.Path
+	problem.RequestID = contextGetRequestID(r)
+
+	return problem
+}
+
 func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error) {
 	app.reportServerError(r, err)
 
 	message := "The server encountered a problem and could not process your request"
-	app.errorMessage(w, r, http.StatusInternalServerError, message, nil)
+	app.errorMessage(w, r, http.StatusInternalServerError, "server_error", message, nil)
 }
 
 func (app *application) notFound(w http.ResponseWriter, r *http.Request) {
 	message := "The requested resource could not be found"
-	app.errorMessage(w, r, http.StatusNotFound, message, nil)
+	app.errorMessage(w, r, http.StatusNotFound, "not_found", message, nil)
 }
 
 func (app *application) methodNotAllowed(w http.ResponseWriter, r *http.Request) {
 	message := fmt.Sprintf("The %s method is not supported for this resource", r.Method)
-	app.errorMessage(w, r, http.StatusMethodNotAllowed, message, nil)
+	app.errorMessage(w, r, http.StatusMethodNotAllowed, "method_not_allowed", message, nil, "method", r.Method)
 }
 
+// badRequest responds with the error's message. The code is taken from a
+// clientError, and is "bad_request" for any other error, such as the ones
+// returned by request.DecodeJSON.
 func (app *application) badRequest(w http.ResponseWriter, r *http.Request, err error) {
-	app.errorMessage(w, r, http.StatusBadRequest, err.Error(), nil)
+	code := "bad_request"
+
+	var ce clientError
+	if errors.As(err, &ce) {
+		code = ce.code
+	}
+
+	app.errorMessage(w, r, http.StatusBadRequest, code, err.Error(), nil)
 }
 
 func (app *application) failedValidation(w http.ResponseWriter, r *http.Request, v validator.Validator) {
-	err := response.JSON(w, http.StatusUnprocessableEntity, v)
+	localizer := app.localizer(r)
+	v = v.Translate(func(e validator.Error) string {
+		return localizer.Translate(e.Code, e.Message, e.Params)
+	})
+	w.Header().Add("Vary", "Accept-Language")
+
+	if !app.config.problemDetails {
+		err := response.JSON(w, http.StatusUnprocessableEntity, v)
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	problem := app.newProblem(r, http.StatusUnprocessableEntity, localizer.Translate("validation_failed", "The request contains invalid data", nil))
+	problem.Code = "validation_failed"
+	problem.Errors = v.Errors
+	problem.FieldErrors = v.FieldErrors
+	problem.ErrorDetails = v.ErrorDetails
+	problem.FieldErrorDetails = v.FieldErrorDetails
+
+	err := response.ProblemJSON(w, problem, nil)
 	if err != nil {
 		app.serverError(w, r, err)
 	}
@@ -69,22 +150,50 @@ func (app *application) invalidAuthenticationToken(w http.ResponseWriter, r *htt
 	headers := make(http.Header)
 	headers.Set("WWW-Authenticate", "Bearer")
 
-	app.errorMessage(w, r, http.StatusUnauthorized, "Invalid authentication token", headers)
+	app.errorMessage(w, r, http.StatusUnauthorized, "invalid_authentication_token", "Invalid authentication token", headers)
+}
+
+func (app *application) invalidRefreshToken(w http.ResponseWriter, r *http.Request) {
+	app.errorMessage(w, r, http.StatusUnauthorized, "invalid_refresh_token", "Invalid or expired refresh token", nil)
 }
 
 func (app *application) authenticationRequired(w http.ResponseWriter, r *http.Request) {
-	app.errorMessage(w, r, http.StatusUnauthorized, "You must be authenticated to access this resource", nil)
+	app.errorMessage(w, r, http.StatusUnauthorized, "authentication_required", "You must be authenticated to access this resource", nil)
 }
 
 func (app *application) unverifiedUser(w http.ResponseWriter, r *http.Request) {
 	message := "Account not verified. Please verify your email address before proceeding."
-	app.errorMessage(w, r, http.StatusForbidden, message, nil)
+	app.errorMessage(w, r, http.StatusForbidden, "unverified_user", message, nil)
+}
+
+func (app *application) notPermitted(w http.ResponseWriter, r *http.Request) {
+	message := "Your user account doesn't have the necessary permissions to access this resource"
+	app.errorMessage(w, r, http.StatusForbidden, "not_permitted", message, nil)
+}
+
+func (app *application) accountDisabled(w http.ResponseWriter, r *http.Request) {
+	message := "Your user account has been disabled"
+	app.errorMessage(w, r, http.StatusForbidden, "account_disabled", message, nil)
+}
+
+// invalidCredentials is the single response to a failed login in privacy
+// mode, whatever the reason.
+func (app *application) invalidCredentials(w http.ResponseWriter, r *http.Request) {
+	app.errorMessage(w, r, http.StatusUnauthorized, "invalid_credentials", "Invalid email address or password", nil)
+}
+
+func (app *application) accountLocked(w http.ResponseWriter, r *http.Request, lockedUntil time.Time) {
+	headers := make(http.Header)
+	headers.Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(lockedUntil).Seconds()))))
+
+	message := "Your user account has been temporarily locked after too many failed login attempts"
+	app.errorMessage(w, r, http.StatusForbidden, "account_locked", message, headers)
 }
 
-func (app *application) basicAuthenticationRequired(w http.ResponseWriter, r *http.Request) {
+func (app *application) rateLimitExceeded(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
 	headers := make(http.Header)
-	headers.Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
+	headers.Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
 
-	message := "You must be authenticated to access this resource"
-	app.errorMessage(w, r, http.StatusUnauthorized, message, headers)
+	message := "Too many requests, please try again later"
+	app.errorMessage(w, r, http.StatusTooManyRequests, "rate_limit_exceeded", message, headers)
 }
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+	"github.com/jcarloasilo/golang-rest-template/internal/dto"
+	"github.com/jcarloasilo/golang-rest-template/internal/request"
+	"github.com/jcarloasilo/golang-rest-template/internal/response"
+	"github.com/jcarloasilo/golang-rest-template/internal/validator"
+)
+
+// handlerDeleteCurrentUser schedules the account for deletion and ends all of
+// its sessions. The account is purged once the grace period has passed,
+// unless the user logs in again before then.
+func (app *application) handlerDeleteCurrentUser(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Password  string              `json:"password"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	user := contextGetAuthenticatedUser(r)
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	err = checkPassword(&input.Validator, "password", input.Password, user.HashedPassword)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	tx, err := app.dbPool.Begin(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	qtx := app.db.WithTx(tx)
+
+	now := time.Now()
+
+	err = qtx.SetUserDeleted(r.Context(), database.SetUserDeletedParams{
+		DeletedAt: now,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = revokeUserSessions(r.Context(), qtx, user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = recordAuditEvent(r, qtx, user.ID, auditAccountDeletionRequested)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = tx.Commit(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]string{
+		"purge_after": now.Add(app.config.accountDeletion.gracePeriod).Format(time.RFC3339),
+	}
+
+	err = response.JSON(w, http.StatusAccepted, data)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// handlerExportCurrentUser streams everything stored about the user as a
+// single JSON document, or as a ZIP archive of JSON files with ?format=zip.
+func (app *application) handlerExportCurrentUser(w http.ResponseWriter, r *http.Request) {
+	var v validator.Validator
+
+	user := contextGetAuthenticatedUser(r)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	v.CheckField(validator.In(format, "json", "zip"), "format", "value.not_allowed", "Must be json or zip", "allowed", []string{"json", "zip"})
+
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	otps, err := app.db.GetUserOTPs(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	auditEvents, err := app.db.GetUserAuditEvents(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	sections := []struct {
+		name string
+		data any
+	}{
+		{"user", dto.NewUser(*user)},
+		{"otps", dto.NewOTPs(otps)},
+		{"audit_events", dto.NewAuditEvents(auditEvents)},
+	}
+
+	filename := "export-" + user.ID.String()
+
+	if format == "json" {
+		data := make(map[string]any, len(sections))
+		for _, s := range sections {
+			data[s.name] = s.data
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+
+		err = enc.Encode(data)
+		if err != nil {
+			app.reportServerError(r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+
+	// Once the first file is written the status code has been sent, so later
+	// errors can only be logged.
+	zw := zip.NewWriter(w)
+
+	for _, s := range sections {
+		fw, err := zw.Create(s.name + ".json")
+		if err != nil {
+			app.reportServerError(r, err)
+			return
+		}
+
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "\t")
+
+		err = enc.Encode(s.data)
+		if err != nil {
+			app.reportServerError(r, err)
+			return
+		}
+	}
+
+	err = zw.Close()
+	if err != nil {
+		app.reportServerError(r, err)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/mailer"
+	"github.com/jcarloasilo/golang-rest-template/internal/response"
+	"github.com/jcarloasilo/golang-rest-template/internal/validator"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (app *application) handlerAdminListEmailTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := mailer.Templates()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]any{"templates": templates})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// handlerAdminPreviewEmailTemplate renders a template with its fixture, in
+// the locale given by ?locale=. With ?format=html it responds with just the
+// HTML body, for viewing in a browser.
+func (app *application) handlerAdminPreviewEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	template, found, err := mailer.LookupTemplate(chi.URLParam(r, "name"))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !found {
+		app.notFound(w, r)
+		return
+	}
+
+	var v validator.Validator
+
+	locale := r.URL.Query().Get("locale")
+	format := r.URL.Query().Get("format")
+
+	v.CheckField(locale == "" || validator.In(locale, template.Locales...), "locale", "locale.unsupported", "Unsupported locale")
+	v.CheckField(validator.In(format, "", "json", "html"), "format", "value.not_allowed", "Must be json or html", "allowed", []string{"json", "html"})
+
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	data, err := mailer.Fixture(template.Name)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	msg, err := app.mailer.Render(locale, data, template.Name+".tmpl")
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if format == "html" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(msg.HTMLBody))
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]string{
+		"subject":    msg.Subject,
+		"plain_body": msg.PlainBody,
+		"html_body":  msg.HTMLBody,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
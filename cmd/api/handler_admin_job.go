@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+	"github.com/jcarloasilo/golang-rest-template/internal/dto"
+	"github.com/jcarloasilo/golang-rest-template/internal/pagination"
+	"github.com/jcarloasilo/golang-rest-template/internal/response"
+	"github.com/jcarloasilo/golang-rest-template/internal/validator"
+)
+
+// handlerAdminGetJobStats reports the number of jobs of each kind in the
+// queue, and what this instance's workers have done with them since it
+// started.
+func (app *application) handlerAdminGetJobStats(w http.ResponseWriter, r *http.Request) {
+	rows, err := app.db.GetJobStats(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]any{
+		"queue":  dto.NewJobStats(rows),
+		"worker": app.jobs.Counts(),
+	}
+
+	err = response.JSON(w, http.StatusOK, data)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// handlerAdminListFailedJobs lists the jobs that ran out of attempts, most
+// recently failed first.
+func (app *application) handlerAdminListFailedJobs(w http.ResponseWriter, r *http.Request) {
+	var v validator.Validator
+
+	p := pagination.Parse(&v, r.URL.Query(), pagination.Options{
+		DefaultSort:  "-failed_at",
+		SortSafelist: []string{"-failed_at"},
+	})
+
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	rows, err := app.db.GetFailedJobs(r.Context(), database.GetFailedJobsParams{
+		PageLimit:  p.Limit(),
+		PageOffset: p.Offset(),
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	var totalRecords int64
+	if len(rows) > 0 {
+		totalRecords = rows[0].TotalCount
+	}
+
+	metadata := pagination.NewMetadata(p, totalRecords, "")
+
+	headers := make(http.Header)
+	headers.Set("Link", pagination.LinkHeader(r.URL, metadata))
+
+	err = response.JSONWithHeaders(w, http.StatusOK, pagination.Envelope{Data: dto.NewJobsFromRows(rows), Metadata: metadata}, headers)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// handlerAdminRetryJob gives a failed job a fresh set of attempts.
+func (app *application) handlerAdminRetryJob(w http.ResponseWriter, r *http.Request) {
+	id, err := readUUIDParam(r, "id")
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	retried, err := app.db.RetryFailedJob(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if retried == 0 {
+		app.notFound(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
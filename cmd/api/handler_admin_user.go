@@ -0,0 +1,408 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+	"github.com/jcarloasilo/golang-rest-template/internal/dto"
+	"github.com/jcarloasilo/golang-rest-template/internal/pagination"
+	"github.com/jcarloasilo/golang-rest-template/internal/request"
+	"github.com/jcarloasilo/golang-rest-template/internal/response"
+	"github.com/jcarloasilo/golang-rest-template/internal/validator"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func (app *application) handlerAdminListUsers(w http.ResponseWriter, r *http.Request) {
+	var v validator.Validator
+
+	p := pagination.Parse(&v, r.URL.Query(), pagination.Options{
+		DefaultSort:        "-created_at",
+		SortSafelist:       []string{"created_at", "-created_at", "email", "-email", "name", "-name"},
+		CursorSortSafelist: []string{"created_at", "-created_at"},
+		FilterSafelist:     []string{"email", "verified", "disabled"},
+	})
+
+	params := database.GetUsersParams{
+		Email:      p.Filter("email"),
+		Verified:   readBoolQuery(&v, p.Filters["verified"], "filter[verified]"),
+		Disabled:   readBoolQuery(&v, p.Filters["disabled"], "filter[disabled]"),
+		Sort:       p.Sort,
+		PageLimit:  p.Limit(),
+		PageOffset: p.Offset(),
+	}
+
+	if p.Cursor != "" {
+		createdAt, id, err := decodeUserCursor(p.Cursor)
+		v.CheckField(err == nil, "cursor", "cursor.invalid", "Invalid cursor")
+
+		params.CursorCreatedAt = &createdAt
+		params.CursorID = &id
+	}
+
+	// Fetch one extra row to find out whether there is a next page, so that
+	// any page can hand the client a cursor to continue from.
+	if p.CursorSupported {
+		params.PageLimit++
+	}
+
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	rows, err := app.db.GetUsers(r.Context(), params)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	var nextCursor string
+	if p.CursorSupported && len(rows) > p.PageSize {
+		rows = rows[:p.PageSize]
+		last := rows[len(rows)-1]
+		nextCursor = pagination.EncodeCursor(last.CreatedAt.Format(time.RFC3339Nano), last.ID.String())
+	}
+
+	var totalRecords int64
+	if len(rows) > 0 {
+		totalRecords = rows[0].TotalCount
+	}
+
+	metadata := pagination.NewMetadata(p, totalRecords, nextCursor)
+
+	headers := make(http.Header)
+	headers.Set("Link", pagination.LinkHeader(r.URL, metadata))
+
+	err = response.JSONWithHeaders(w, http.StatusOK, pagination.Envelope{Data: dto.NewUsersFromRows(rows), Metadata: metadata}, headers)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// decodeUserCursor unpacks a cursor pointing at the created_at and id of the
+// last user on the previous page.
+func decodeUserCursor(cursor string) (time.Time, uuid.UUID, error) {
+	values, err := pagination.DecodeCursor(cursor, 2)
+	if err != nil {
+		return time.Time{}, uuid.UUID{}, err
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, values[0])
+	if err != nil {
+		return time.Time{}, uuid.UUID{}, err
+	}
+
+	id, err := uuid.Parse(values[1])
+	if err != nil {
+		return time.Time{}, uuid.UUID{}, err
+	}
+
+	return createdAt, id, nil
+}
+
+func (app *application) handlerAdminGetUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.userFromURLParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := response.JSON(w, http.StatusOK, dto.NewUser(user))
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) handlerAdminUpdateUser(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name      *string             `json:"name"`
+		Email     *string             `json:"email"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	user, ok := app.userFromURLParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		input.Validator.CheckField(validator.NotBlank(*input.Name), "name", "name.required", "Name is required")
+		input.Validator.CheckField(validator.MaxRunes(*input.Name, 255), "name", "name.too_long", "Name is too long", "max", 255)
+
+		user.Name = *input.Name
+	}
+
+	emailChanged := input.Email != nil && *input.Email != user.Email
+
+	if emailChanged {
+		existingUser, err := app.db.GetUserByEmail(r.Context(), *input.Email)
+		notExist := errors.Is(err, pgx.ErrNoRows)
+		if err != nil && !notExist {
+			app.serverError(w, r, err)
+			return
+		}
+
+		input.Validator.CheckField(notExist || existingUser.ID == user.ID, "email", "email.taken", "Email is already in use")
+
+		user.Email = *input.Email
+	}
+
+	input.Validator.CheckField(user.Email != "", "email", "email.required", "Email is required")
+	input.Validator.CheckField(validator.Matches(user.Email, validator.RgxEmail), "email", "email.invalid", "Must be a valid email address")
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	tx, err := app.dbPool.Begin(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	qtx := app.db.WithTx(tx)
+
+	user, err = qtx.UpdateUser(r.Context(), database.UpdateUserParams{
+		Name:   user.Name,
+		Email:  user.Email,
+		UserID: user.ID,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// A new address set by an admin replaces any change the user had
+	// started themselves, so that their code can't overwrite it.
+	if emailChanged {
+		err = qtx.SetUserPendingEmail(r.Context(), database.SetUserPendingEmailParams{
+			PendingEmail: nil,
+			UserID:       user.ID,
+		})
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		user.PendingEmail = nil
+
+		err = qtx.InvalidateExistingOTP(r.Context(), database.InvalidateExistingOTPParams{
+			UserID: user.ID,
+			Type:   database.OtpTypeEmailChange,
+		})
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		err = recordAuditEvent(r, qtx, user.ID, auditEmailChanged)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	err = tx.Commit(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, dto.NewUser(user))
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// handlerAdminDisableUser blocks the user from logging in and ends all of
+// their existing sessions.
+func (app *application) handlerAdminDisableUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.userFromURLParam(w, r)
+	if !ok {
+		return
+	}
+
+	if user.ID == contextGetAuthenticatedUser(r).ID {
+		app.badRequest(w, r, errCannotDisableSelf)
+		return
+	}
+
+	tx, err := app.dbPool.Begin(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	qtx := app.db.WithTx(tx)
+
+	now := time.Now()
+
+	err = qtx.SetUserDisabled(r.Context(), database.SetUserDisabledParams{
+		DisabledAt: &now,
+		UserID:     user.ID,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = revokeUserSessions(r.Context(), qtx, user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = tx.Commit(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) handlerAdminEnableUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.userFromURLParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.db.SetUserDisabled(r.Context(), database.SetUserDisabledParams{
+		DisabledAt: nil,
+		UserID:     user.ID,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerAdminUnlockUser lifts a lockout caused by failed logins and resets
+// the count of failed attempts.
+func (app *application) handlerAdminUnlockUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.userFromURLParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.db.ResetFailedLogins(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = recordAuditEvent(r, app.db, user.ID, auditAccountUnlocked)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) handlerAdminVerifyUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.userFromURLParam(w, r)
+	if !ok {
+		return
+	}
+
+	if user.VerifiedAt == nil {
+		err := app.db.VerifyUser(r.Context(), database.VerifyUserParams{
+			UserID:     user.ID,
+			VerifiedAt: time.Now(),
+		})
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		err = app.db.InvalidateExistingOTP(r.Context(), database.InvalidateExistingOTPParams{
+			UserID: user.ID,
+			Type:   database.OtpTypeEmailVerification,
+		})
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) handlerAdminResendVerification(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.userFromURLParam(w, r)
+	if !ok {
+		return
+	}
+
+	if user.VerifiedAt != nil {
+		app.badRequest(w, r, errUserAlreadyVerified)
+		return
+	}
+
+	tx, err := app.dbPool.Begin(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	qtx := app.db.WithTx(tx)
+
+	// Admins aren't held to the resend cooldown.
+	err = qtx.InvalidateExistingOTP(r.Context(), database.InvalidateExistingOTPParams{
+		UserID: user.ID,
+		Type:   database.OtpTypeEmailVerification,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.queueOTPEmail(r.Context(), qtx, user.ID, database.OtpTypeEmailVerification, user.Locale)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = tx.Commit(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) handlerAdminDeleteUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.userFromURLParam(w, r)
+	if !ok {
+		return
+	}
+
+	if user.ID == contextGetAuthenticatedUser(r).ID {
+		app.badRequest(w, r, errCannotDeleteSelf)
+		return
+	}
+
+	err := app.db.DeleteUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,16 +1,18 @@
 package main
 
 import (
-	"database/sql"
+	"errors"
 	"net/http"
 	"time"
 
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
 	"github.com/jcarloasilo/golang-rest-template/internal/password"
 	"github.com/jcarloasilo/golang-rest-template/internal/request"
 	"github.com/jcarloasilo/golang-rest-template/internal/response"
 	"github.com/jcarloasilo/golang-rest-template/internal/validator"
 
-	"github.com/pascaldekloe/jwt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 func (app *application) handlerLogin(w http.ResponseWriter, r *http.Request) {
@@ -26,53 +28,236 @@ func (app *application) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !app.allowAccount(w, r, "login", app.config.rateLimit.loginAccount, input.Email) {
+		return
+	}
+
 	user, err := app.db.GetUserByEmail(r.Context(), input.Email)
+	userFound := err == nil
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// In privacy mode a locked account looks just like a wrong password, so
+	// that the lockout doesn't reveal that the account exists.
+	locked := userLocked(user)
+	if locked && !app.config.privacyMode {
+		app.accountLocked(w, r, *user.LockedUntil)
+		return
+	}
+
+	input.Validator.CheckField(input.Email != "", "email", "email.required", "Email is required")
+	input.Validator.CheckField(input.Password != "", "password", "password.required", "Password is required")
+
+	// Always pay for a bcrypt comparison, so that the response time doesn't
+	// give away whether the email address is registered.
+	var passwordMatches bool
+	if userFound && !locked {
+		passwordMatches, err = password.Matches(input.Password, user.HashedPassword)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	} else {
+		password.MatchesDummy(input.Password)
+	}
+
+	if userFound && !locked && input.Password != "" && !passwordMatches {
+		_, err = app.recordFailedLogin(r, user)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	if !input.Validator.HasErrors() && !passwordMatches {
+		if app.config.privacyMode {
+			app.invalidCredentials(w, r)
+			return
+		}
+
+		if userFound {
+			input.Validator.AddFieldError("password", "password.incorrect", "Password is incorrect")
+		} else {
+			input.Validator.AddFieldError("email", "email.not_found", "Email address could not be found")
+		}
+	}
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	if user.DisabledAt != nil {
+		app.accountDisabled(w, r)
+		return
+	}
+
+	_, hasTOTP, err := app.getConfirmedTOTPCredential(r.Context(), user.ID)
 	if err != nil {
-		switch err {
-		case sql.ErrNoRows:
-			input.Validator.CheckField(false, "email", "Email address could not be found")
-		default:
+		app.serverError(w, r, err)
+		return
+	}
+
+	if hasTOTP {
+		app.mfaChallenge(w, r, user.ID, "totp")
+		return
+	}
+
+	if user.TwoFactorEnabled {
+		app.startTwoFactorLogin(w, r, user)
+		return
+	}
+
+	err = app.resetFailedLogins(r, user)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.startSession(w, r, user.ID)
+}
+
+// startSession issues an authentication token and a refresh token in a new
+// token family and writes them to the response. Logging in during the
+// deletion grace period cancels the pending account deletion.
+func (app *application) startSession(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	restored, err := app.db.RestoreDeletedUser(r.Context(), userID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if restored > 0 {
+		err = recordAuditEvent(r, app.db, userID, auditAccountDeletionCancelled)
+		if err != nil {
 			app.serverError(w, r, err)
 			return
 		}
 	}
 
-	input.Validator.CheckField(input.Email != "", "email", "Email is required")
+	err = recordAuditEvent(r, app.db, userID, auditLogin)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 
-	passwordMatches, err := password.Matches(input.Password, user.HashedPassword)
+	authenticationToken, authenticationTokenExpiry, err := app.newAuthenticationToken(r.Context(), userID)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	input.Validator.CheckField(input.Password != "", "password", "Password is required")
-	input.Validator.CheckField(passwordMatches, "password", "Password is incorrect")
+	refreshToken, refreshTokenRecord, err := app.newRefreshToken(r.Context(), app.db, userID, uuid.New())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]string{
+		"authentication_token":        authenticationToken,
+		"authentication_token_expiry": authenticationTokenExpiry.Format(time.RFC3339),
+		"refresh_token":               refreshToken,
+		"refresh_token_expiry":        refreshTokenRecord.ExpiresAt.Format(time.RFC3339),
+	}
+
+	err = response.JSON(w, http.StatusOK, data)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) handlerRefreshToken(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RefreshToken string              `json:"refresh_token"`
+		Validator    validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Validator.CheckField(input.RefreshToken != "", "refresh_token", "refresh_token.required", "Refresh token is required")
 
 	if input.Validator.HasErrors() {
 		app.failedValidation(w, r, input.Validator)
 		return
 	}
 
-	var claims jwt.Claims
-	claims.Subject = user.ID.String()
+	existingToken, err := app.db.GetRefreshTokenByHash(r.Context(), hashRefreshToken(input.RefreshToken))
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			app.invalidRefreshToken(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
 
-	expiry := time.Now().Add(24 * time.Hour)
-	claims.Issued = jwt.NewNumericTime(time.Now())
-	claims.NotBefore = jwt.NewNumericTime(time.Now())
-	claims.Expires = jwt.NewNumericTime(expiry)
+	// A refresh token that has already been rotated or revoked is being
+	// replayed, so treat the whole token family as compromised.
+	if existingToken.RevokedAt != nil {
+		app.revokeRefreshTokenFamily(w, r, existingToken)
+		return
+	}
 
-	claims.Issuer = app.config.baseURL
-	claims.Audiences = []string{app.config.baseURL}
+	if existingToken.ExpiresAt.Before(time.Now()) {
+		app.invalidRefreshToken(w, r)
+		return
+	}
+
+	tx, err := app.dbPool.Begin(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	qtx := app.db.WithTx(tx)
 
-	jwtBytes, err := claims.HMACSign(jwt.HS256, []byte(app.config.jwt.secretKey))
+	refreshToken, refreshTokenRecord, err := app.newRefreshToken(r.Context(), qtx, existingToken.UserID, existingToken.FamilyID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	rowsAffected, err := qtx.RotateRefreshToken(r.Context(), database.RotateRefreshTokenParams{
+		ReplacedBy: refreshTokenRecord.ID,
+		ID:         existingToken.ID,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Another request rotated the same token concurrently.
+	if rowsAffected == 0 {
+		tx.Rollback(r.Context())
+		app.revokeRefreshTokenFamily(w, r, existingToken)
+		return
+	}
+
+	err = tx.Commit(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	authenticationToken, authenticationTokenExpiry, err := app.newAuthenticationToken(r.Context(), existingToken.UserID)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
 	data := map[string]string{
-		"authentication_token":        string(jwtBytes),
-		"authentication_token_expiry": expiry.Format(time.RFC3339),
+		"authentication_token":        authenticationToken,
+		"authentication_token_expiry": authenticationTokenExpiry.Format(time.RFC3339),
+		"refresh_token":               refreshToken,
+		"refresh_token_expiry":        refreshTokenRecord.ExpiresAt.Format(time.RFC3339),
 	}
 
 	err = response.JSON(w, http.StatusOK, data)
@@ -80,3 +265,81 @@ func (app *application) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		app.serverError(w, r, err)
 	}
 }
+
+func (app *application) revokeRefreshTokenFamily(w http.ResponseWriter, r *http.Request, token database.RefreshToken) {
+	err := app.db.RevokeRefreshTokenFamily(r.Context(), token.FamilyID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.logger.Warn("refresh token reuse detected", "user_id", token.UserID, "family_id", token.FamilyID)
+	app.invalidRefreshToken(w, r)
+}
+
+func (app *application) handlerLogout(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if r.ContentLength != 0 {
+		err := request.DecodeJSON(w, r, &input)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+	}
+
+	user := contextGetAuthenticatedUser(r)
+
+	err := app.revokeAuthenticationToken(r.Context(), user.ID, contextGetAuthenticationClaims(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if input.RefreshToken != "" {
+		refreshToken, err := app.db.GetRefreshTokenByHash(r.Context(), hashRefreshToken(input.RefreshToken))
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+		case err != nil:
+			app.serverError(w, r, err)
+			return
+		case refreshToken.UserID == user.ID:
+			err = app.db.RevokeRefreshTokenFamily(r.Context(), refreshToken.FamilyID)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) handlerLogoutAll(w http.ResponseWriter, r *http.Request) {
+	user := contextGetAuthenticatedUser(r)
+
+	tx, err := app.dbPool.Begin(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	qtx := app.db.WithTx(tx)
+
+	err = revokeUserSessions(r.Context(), qtx, user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = tx.Commit(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,11 +1,9 @@
 package main
 
 import (
-	"errors"
 	"net/http"
 	"time"
 
-	"github.com/jackc/pgx/v5"
 	"github.com/jcarloasilo/golang-rest-template/internal/database"
 	"github.com/jcarloasilo/golang-rest-template/internal/request"
 	"github.com/jcarloasilo/golang-rest-template/internal/response"
@@ -26,37 +24,14 @@ func (app *application) handlerEmailConfirmation(w http.ResponseWriter, r *http.
 		return
 	}
 
-	existingOTP, err := app.db.GetLatestOTP(r.Context(), database.GetLatestOTPParams{
-		UserID: user.ID,
-		Type:   database.OtpTypeEmailVerification,
-	})
-	if err != nil {
-		app.notFound(w, r)
-		return
-	}
+	input.Validator.CheckField(input.Code != "", "code", "code.required", "Code is required")
 
-	if existingOTP.ExpiresAt.Before(time.Now()) {
-		app.badRequest(w, r, errors.New("expired otp"))
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
 		return
 	}
 
-	if existingOTP.Attempts >= existingOTP.MaxAttempts {
-		app.badRequest(w, r, errors.New("too many failed attempts"))
-		return
-	}
-
-	if input.Code != existingOTP.Code {
-		input.Validator.CheckField(false, "code", "Invalid OTP")
-
-		err = app.db.IncrementOTPAttempts(r.Context(), existingOTP.ID)
-		if err != nil {
-			app.serverError(w, r, err)
-			return
-		}
-	}
-
-	if input.Validator.HasErrors() {
-		app.failedValidation(w, r, input.Validator)
+	if !app.checkOTP(w, r, user.ID, database.OtpTypeEmailVerification, input.Code) {
 		return
 	}
 
@@ -79,70 +54,16 @@ func (app *application) handlerEmailConfirmation(w http.ResponseWriter, r *http.
 func (app *application) handlerNewEmailConfirmation(w http.ResponseWriter, r *http.Request) {
 	user := contextGetAuthenticatedUser(r)
 
-	existingOTP, err := app.db.GetLatestOTP(r.Context(), database.GetLatestOTPParams{
-		UserID: user.ID,
-		Type:   database.OtpTypeEmailVerification,
-	})
-	noExistingOTP := errors.Is(err, pgx.ErrNoRows)
-	if err != nil && !noExistingOTP {
-		app.serverError(w, r, err)
-		return
-	}
-
-	if !noExistingOTP && !existingOTP.ExpiresAt.Before(time.Now()) {
-		app.badRequest(w, r, errors.New("a valid otp is already active"))
+	if !app.allowAccount(w, r, "otp_email", app.config.rateLimit.otpEmail, user.Email) {
 		return
 	}
 
-	if !noExistingOTP {
-		err = app.db.InvalidateExistingOTP(r.Context(), database.InvalidateExistingOTPParams{
-			UserID: user.ID,
-			Type:   database.OtpTypeEmailVerification,
-		})
-		if err != nil {
-			app.serverError(w, r, err)
-			return
-		}
-	}
-
-	otp, err := app.generateOTP(6)
+	err := app.queueOTPEmail(r.Context(), app.db, user.ID, database.OtpTypeEmailVerification, app.localizerFor(r, user).Locale())
 	if err != nil {
-		app.serverError(w, r, err)
+		app.otpIssueError(w, r, err)
 		return
 	}
 
-	now := time.Now()
-
-	err = app.db.CreateOTP(r.Context(), database.CreateOTPParams{
-		Code:      otp,
-		Type:      database.OtpTypeEmailVerification,
-		UserID:    user.ID,
-		CreatedAt: now,
-		ExpiresAt: now.Add(time.Minute * 5),
-	})
-	if err != nil {
-		app.serverError(w, r, err)
-		return
-	}
-
-	app.backgroundTask(r, func() error {
-		type EmailData struct {
-			Name string
-			Code string
-		}
-
-		err = app.mailer.Send(user.Email, EmailData{
-			Name: user.Name,
-			Code: otp,
-		}, "email_confirmation.tmpl")
-
-		if err != nil {
-			return err
-		}
-
-		return nil
-	})
-
 	err = response.JSON(w, http.StatusNoContent, nil)
 	if err != nil {
 		app.serverError(w, r, err)
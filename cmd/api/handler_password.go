@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+	"github.com/jcarloasilo/golang-rest-template/internal/password"
+	"github.com/jcarloasilo/golang-rest-template/internal/request"
+	"github.com/jcarloasilo/golang-rest-template/internal/validator"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (app *application) handlerPasswordResetRequest(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email     string              `json:"email"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Validator.CheckField(input.Email != "", "email", "email.required", "Email is required")
+	input.Validator.CheckField(validator.Matches(input.Email, validator.RgxEmail), "email", "email.invalid", "Must be a valid email address")
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	if !app.allowAccount(w, r, "otp_email", app.config.rateLimit.otpEmail, input.Email) {
+		return
+	}
+
+	// Respond identically whether or not the email belongs to an account, so
+	// this endpoint cannot be used to discover registered addresses.
+	user, err := app.db.GetUserByEmail(r.Context(), input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// A code sent within the cooldown is still valid, so a repeated request
+	// is answered as if it had succeeded rather than revealing the account.
+	err = app.queueOTPEmail(r.Context(), app.db, user.ID, database.OtpTypePasswordReset, app.localizerFor(r, &user).Locale())
+	if err != nil && !isOTPCooldown(err) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) handlerPasswordResetConfirm(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email     string              `json:"email"`
+		Code      string              `json:"code"`
+		Password  string              `json:"password"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Validator.CheckField(input.Email != "", "email", "email.required", "Email is required")
+	input.Validator.CheckField(input.Code != "", "code", "code.required", "Code is required")
+	validatePassword(&input.Validator, "password", input.Password)
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	user, err := app.db.GetUserByEmail(r.Context(), input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			input.Validator.AddFieldError("code", "code.invalid", "Invalid OTP")
+			app.failedValidation(w, r, input.Validator)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if !app.checkOTP(w, r, user.ID, database.OtpTypePasswordReset, input.Code) {
+		return
+	}
+
+	hashedPassword, err := password.Hash(input.Password)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	tx, err := app.dbPool.Begin(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	qtx := app.db.WithTx(tx)
+
+	err = qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		HashedPassword: hashedPassword,
+		UserID:         user.ID,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = revokeUserSessions(r.Context(), qtx, user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = recordAuditEvent(r, qtx, user.ID, auditPasswordReset)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = tx.Commit(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+	"github.com/jcarloasilo/golang-rest-template/internal/response"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+func (app *application) handlerListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := app.db.GetRoles(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	rolePermissions, err := app.db.GetRolePermissionNames(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	permissions := make(map[string][]string)
+	for _, rp := range rolePermissions {
+		permissions[rp.RoleName] = append(permissions[rp.RoleName], rp.PermissionName)
+	}
+
+	type roleData struct {
+		Name        string   `json:"name"`
+		Description string   `json:"description"`
+		Permissions []string `json:"permissions"`
+	}
+
+	data := make([]roleData, len(roles))
+	for i, role := range roles {
+		data[i] = roleData{
+			Name:        role.Name,
+			Description: role.Description,
+			Permissions: permissions[role.Name],
+		}
+
+		if data[i].Permissions == nil {
+			data[i].Permissions = []string{}
+		}
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]any{"roles": data})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) handlerGetUserRoles(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.userFromURLParam(w, r)
+	if !ok {
+		return
+	}
+
+	roles, err := app.db.GetUserRoleNames(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if roles == nil {
+		roles = []string{}
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string][]string{"roles": roles})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) handlerAssignUserRole(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.userFromURLParam(w, r)
+	if !ok {
+		return
+	}
+
+	role, ok := app.roleFromURLParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.db.AssignUserRole(r.Context(), database.AssignUserRoleParams{
+		UserID: user.ID,
+		RoleID: role.ID,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) handlerRemoveUserRole(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.userFromURLParam(w, r)
+	if !ok {
+		return
+	}
+
+	role, ok := app.roleFromURLParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.db.RemoveUserRole(r.Context(), database.RemoveUserRoleParams{
+		UserID: user.ID,
+		RoleID: role.ID,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// userFromURLParam looks up the user identified by the {id} URL parameter,
+// responding with 404 Not Found if there is no such user.
+func (app *application) userFromURLParam(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	userID, err := readUUIDParam(r, "id")
+	if err != nil {
+		app.notFound(w, r)
+		return database.User{}, false
+	}
+
+	user, err := app.db.GetUser(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return database.User{}, false
+	}
+
+	return user, true
+}
+
+func (app *application) roleFromURLParam(w http.ResponseWriter, r *http.Request) (database.Role, bool) {
+	role, err := app.db.GetRoleByName(r.Context(), chi.URLParam(r, "role"))
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return database.Role{}, false
+	}
+
+	return role, true
+}
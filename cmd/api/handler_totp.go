@@ -0,0 +1,398 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+	"github.com/jcarloasilo/golang-rest-template/internal/request"
+	"github.com/jcarloasilo/golang-rest-template/internal/response"
+	"github.com/jcarloasilo/golang-rest-template/internal/totp"
+	"github.com/jcarloasilo/golang-rest-template/internal/validator"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	totpSkew          = 1
+	recoveryCodeCount = 10
+)
+
+// handlerEnrollTOTP asks for the password, like the other changes to a
+// user's second factors, so that a stolen access token can't be used to bind
+// another authenticator app to the account.
+func (app *application) handlerEnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Password  string              `json:"password"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := contextGetAuthenticatedUser(r)
+
+	_, hasTOTP, err := app.getConfirmedTOTPCredential(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if hasTOTP {
+		app.badRequest(w, r, errTOTPAlreadyEnabled)
+		return
+	}
+
+	err = checkPassword(&input.Validator, "password", input.Password, user.HashedPassword)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.db.UpsertTOTPCredential(r.Context(), database.UpsertTOTPCredentialParams{
+		UserID: user.ID,
+		Secret: secret,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]string{
+		"secret":      secret,
+		"otpauth_uri": totp.URI(app.config.totp.issuer, user.Email, secret),
+	}
+
+	err = response.JSON(w, http.StatusOK, data)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) handlerConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code      string              `json:"code"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := contextGetAuthenticatedUser(r)
+
+	credential, err := app.db.GetTOTPCredential(r.Context(), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if credential.ConfirmedAt != nil {
+		app.badRequest(w, r, errTOTPAlreadyEnabled)
+		return
+	}
+
+	step, valid, err := totp.Validate(credential.Secret, input.Code, time.Now(), totpSkew, credential.LastUsedStep)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	input.Validator.CheckField(input.Code != "", "code", "code.required", "Code is required")
+	input.Validator.CheckField(valid, "code", "code.invalid", "Invalid code")
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	tx, err := app.dbPool.Begin(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	qtx := app.db.WithTx(tx)
+
+	err = qtx.ConfirmTOTPCredential(r.Context(), database.ConfirmTOTPCredentialParams{
+		LastUsedStep: step,
+		UserID:       user.ID,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	recoveryCodes, err := replaceRecoveryCodes(r.Context(), qtx, user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = recordAuditEvent(r, qtx, user.ID, auditTOTPEnabled)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = tx.Commit(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string][]string{
+		"recovery_codes": recoveryCodes,
+	}
+
+	err = response.JSON(w, http.StatusOK, data)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) handlerDisableTOTP(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Password  string              `json:"password"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := contextGetAuthenticatedUser(r)
+
+	err = checkPassword(&input.Validator, "password", input.Password, user.HashedPassword)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	tx, err := app.dbPool.Begin(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	qtx := app.db.WithTx(tx)
+
+	err = qtx.DeleteTOTPCredential(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = qtx.DeleteRecoveryCodes(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = recordAuditEvent(r, qtx, user.ID, auditTOTPDisabled)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = tx.Commit(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) handlerRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Password  string              `json:"password"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := contextGetAuthenticatedUser(r)
+
+	_, hasTOTP, err := app.getConfirmedTOTPCredential(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !hasTOTP {
+		app.badRequest(w, r, errTOTPNotEnabled)
+		return
+	}
+
+	err = checkPassword(&input.Validator, "password", input.Password, user.HashedPassword)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	tx, err := app.dbPool.Begin(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	recoveryCodes, err := replaceRecoveryCodes(r.Context(), app.db.WithTx(tx), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = tx.Commit(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string][]string{
+		"recovery_codes": recoveryCodes,
+	}
+
+	err = response.JSON(w, http.StatusOK, data)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) getConfirmedTOTPCredential(ctx context.Context, userID uuid.UUID) (database.TotpCredential, bool, error) {
+	credential, err := app.db.GetTOTPCredential(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.TotpCredential{}, false, nil
+		}
+		return database.TotpCredential{}, false, err
+	}
+
+	return credential, credential.ConfirmedAt != nil, nil
+}
+
+// verifyTOTPLogin checks either an authenticator app code or an unused
+// recovery code, and marks whichever was given as used.
+func (app *application) verifyTOTPLogin(ctx context.Context, userID uuid.UUID, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		rowsAffected, err := app.db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(recoveryCode),
+		})
+		if err != nil {
+			return false, err
+		}
+
+		return rowsAffected == 1, nil
+	}
+
+	credential, err := app.db.GetTOTPCredential(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	step, valid, err := totp.Validate(credential.Secret, code, time.Now(), totpSkew, credential.LastUsedStep)
+	if err != nil || !valid {
+		return false, err
+	}
+
+	// Only accept each time step once, so an observed code cannot be replayed
+	// even by a concurrent request.
+	rowsAffected, err := app.db.UseTOTPStep(ctx, database.UseTOTPStepParams{
+		Step:   step,
+		UserID: userID,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// replaceRecoveryCodes discards the user's recovery codes and stores a fresh
+// set, returning the plaintext codes so they can be shown to the user once.
+func replaceRecoveryCodes(ctx context.Context, db *database.Queries, userID uuid.UUID) ([]string, error) {
+	err := db.DeleteRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	recoveryCodes := make([]string, recoveryCodeCount)
+
+	for i := range recoveryCodes {
+		randomBytes := make([]byte, 10)
+
+		_, err := rand.Read(randomBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		code := strings.ToLower(encoding.EncodeToString(randomBytes))
+		recoveryCodes[i] = code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16]
+
+		err = db.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(recoveryCodes[i]),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return recoveryCodes, nil
+}
+
+func hashRecoveryCode(code string) []byte {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+
+	hash := sha256.Sum256([]byte(normalized))
+	return hash[:]
+}
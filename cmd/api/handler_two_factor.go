@@ -0,0 +1,253 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+	"github.com/jcarloasilo/golang-rest-template/internal/request"
+	"github.com/jcarloasilo/golang-rest-template/internal/response"
+	"github.com/jcarloasilo/golang-rest-template/internal/validator"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pascaldekloe/jwt"
+)
+
+// startTwoFactorLogin emails a two_factor_auth OTP to a user who has passed
+// the password check and responds with a short-lived MFA token in place of
+// the authentication token.
+func (app *application) startTwoFactorLogin(w http.ResponseWriter, r *http.Request, user database.User) {
+	err := app.queueOTPEmail(r.Context(), app.db, user.ID, database.OtpTypeTwoFactorAuth, app.localizerFor(r, &user).Locale())
+	if isOTPCooldown(err) {
+		// A code sent within the cooldown can still be used unless it has been
+		// consumed or run out of attempts, so the login goes on without
+		// sending another. Otherwise the client must wait for the cooldown.
+		usable, usableErr := app.otps.HasUsable(r.Context(), user.ID, database.OtpTypeTwoFactorAuth)
+		if usableErr != nil {
+			app.serverError(w, r, usableErr)
+			return
+		}
+
+		if !usable {
+			app.otpIssueError(w, r, err)
+			return
+		}
+
+		err = nil
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.mfaChallenge(w, r, user.ID, "email")
+}
+
+// mfaChallenge responds with an MFA token that the client must exchange,
+// together with a code for the given method, at POST /login/2fa.
+func (app *application) mfaChallenge(w http.ResponseWriter, r *http.Request, userID uuid.UUID, method string) {
+	mfaToken, mfaTokenExpiry, err := app.newMFAToken(userID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]string{
+		"mfa_method":       method,
+		"mfa_token":        mfaToken,
+		"mfa_token_expiry": mfaTokenExpiry.Format(time.RFC3339),
+	}
+
+	err = response.JSON(w, http.StatusOK, data)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) handlerLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		MFAToken     string              `json:"mfa_token"`
+		Code         string              `json:"code"`
+		RecoveryCode string              `json:"recovery_code"`
+		Validator    validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Validator.CheckField(input.MFAToken != "", "mfa_token", "mfa_token.required", "MFA token is required")
+	input.Validator.CheckField(input.Code != "" || input.RecoveryCode != "", "code", "code.required", "Code is required")
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	claims, err := app.parseToken(input.MFAToken, app.mfaAudience())
+	if err != nil {
+		app.invalidAuthenticationToken(w, r)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	user, err := app.db.GetUser(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			app.invalidAuthenticationToken(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if sessionRevoked(user, claims) {
+		app.invalidAuthenticationToken(w, r)
+		return
+	}
+
+	// The account may have been disabled or locked since the password check.
+	if user.DisabledAt != nil {
+		app.accountDisabled(w, r)
+		return
+	}
+
+	if userLocked(user) {
+		app.accountLocked(w, r, *user.LockedUntil)
+		return
+	}
+
+	_, hasTOTP, err := app.getConfirmedTOTPCredential(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if hasTOTP {
+		valid, err := app.verifyTOTPLogin(r.Context(), user.ID, input.Code, input.RecoveryCode)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if !valid {
+			err = app.recordFailedMFA(r, user, claims)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+
+			input.Validator.AddFieldError("code", "code.invalid", "Invalid code")
+			app.failedValidation(w, r, input.Validator)
+			return
+		}
+
+		app.finishTwoFactorLogin(w, r, user, claims)
+		return
+	}
+
+	err = app.otps.Verify(r.Context(), user.ID, database.OtpTypeTwoFactorAuth, input.Code)
+	if err != nil {
+		if isOTPRejected(err) {
+			err := app.recordFailedMFA(r, user, claims)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+		}
+
+		app.otpVerifyError(w, r, err)
+		return
+	}
+
+	app.finishTwoFactorLogin(w, r, user, claims)
+}
+
+// recordFailedMFA counts a wrong second-factor code as a failed login, so
+// that it feeds the lockout. Once the user has failed mfaMaxAttempts times
+// in a row, the MFA token is revoked too, so that one token can't be used to
+// keep guessing codes until it expires.
+func (app *application) recordFailedMFA(r *http.Request, user database.User, mfaClaims *jwt.Claims) error {
+	failedAttempts, err := app.recordFailedLogin(r, user)
+	if err != nil {
+		return err
+	}
+
+	if failedAttempts < mfaMaxAttempts {
+		return nil
+	}
+
+	return app.revokeAuthenticationToken(r.Context(), user.ID, mfaClaims)
+}
+
+func (app *application) finishTwoFactorLogin(w http.ResponseWriter, r *http.Request, user database.User, mfaClaims *jwt.Claims) {
+	// The MFA token is single use.
+	err := app.revokeAuthenticationToken(r.Context(), user.ID, mfaClaims)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.resetFailedLogins(r, user)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.startSession(w, r, user.ID)
+}
+
+func (app *application) handlerEnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	app.setTwoFactorEnabled(w, r, true)
+}
+
+func (app *application) handlerDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	app.setTwoFactorEnabled(w, r, false)
+}
+
+func (app *application) setTwoFactorEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	var input struct {
+		Password  string              `json:"password"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := contextGetAuthenticatedUser(r)
+
+	err = checkPassword(&input.Validator, "password", input.Password, user.HashedPassword)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	err = app.db.SetUserTwoFactorEnabled(r.Context(), database.SetUserTwoFactorEnabledParams{
+		TwoFactorEnabled: enabled,
+		UserID:           user.ID,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
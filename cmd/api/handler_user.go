@@ -3,9 +3,11 @@ package main
 import (
 	"errors"
 	"net/http"
-	"time"
 
 	"github.com/jcarloasilo/golang-rest-template/internal/database"
+	"github.com/jcarloasilo/golang-rest-template/internal/dto"
+	"github.com/jcarloasilo/golang-rest-template/internal/i18n"
+	"github.com/jcarloasilo/golang-rest-template/internal/jobs"
 	"github.com/jcarloasilo/golang-rest-template/internal/password"
 	"github.com/jcarloasilo/golang-rest-template/internal/request"
 	"github.com/jcarloasilo/golang-rest-template/internal/response"
@@ -28,21 +30,22 @@ func (app *application) handlerCreateUser(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	_, err = app.db.GetUserByEmail(r.Context(), input.Email)
+	if !app.allowAccount(w, r, "otp_email", app.config.rateLimit.otpEmail, input.Email) {
+		return
+	}
+
+	existingUser, err := app.db.GetUserByEmail(r.Context(), input.Email)
 	notExist := errors.Is(err, pgx.ErrNoRows)
 	if err != nil && !notExist {
 		app.serverError(w, r, err)
 		return
 	}
 
-	input.Validator.CheckField(input.Email != "", "email", "Email is required")
-	input.Validator.CheckField(validator.Matches(input.Email, validator.RgxEmail), "email", "Must be a valid email address")
-	input.Validator.CheckField(notExist, "email", "Email is already in use")
+	input.Validator.CheckField(input.Email != "", "email", "email.required", "Email is required")
+	input.Validator.CheckField(validator.Matches(input.Email, validator.RgxEmail), "email", "email.invalid", "Must be a valid email address")
+	input.Validator.CheckField(notExist || app.config.privacyMode, "email", "email.taken", "Email is already in use")
 
-	input.Validator.CheckField(input.Password != "", "password", "Password is required")
-	input.Validator.CheckField(len(input.Password) >= 8, "password", "Password is too short")
-	input.Validator.CheckField(len(input.Password) <= 72, "password", "Password is too long")
-	input.Validator.CheckField(validator.NotIn(input.Password, password.CommonPasswords...), "password", "Password is too common")
+	validatePassword(&input.Validator, "password", input.Password)
 
 	if input.Validator.HasErrors() {
 		app.failedValidation(w, r, input.Validator)
@@ -55,62 +58,359 @@ func (app *application) handlerCreateUser(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	user, err := app.db.CreateUser(r.Context(), database.CreateUserParams{
+	// In privacy mode, registering an email address that is already in use
+	// looks like it succeeded, and the owner of the address is told about the
+	// attempt instead.
+	if !notExist {
+		locale := app.localizerFor(r, &existingUser).Locale()
+
+		type EmailData struct {
+			Name string
+		}
+
+		err = app.queueEmail(r.Context(), existingUser.Email, locale, EmailData{
+			Name: existingUser.Name,
+		}, jobs.EnqueueOptions{}, "registration_attempt.tmpl")
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// The user and the job that sends their verification email are committed
+	// together, so the email is sent even if this process stops.
+	tx, err := app.dbPool.Begin(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	qtx := app.db.WithTx(tx)
+
+	user, err := qtx.CreateUser(r.Context(), database.CreateUserParams{
 		Email:          input.Email,
 		Name:           input.Name,
 		HashedPassword: hashedPassword,
 	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 
+	err = app.queueOTPEmail(r.Context(), qtx, user.ID, database.OtpTypeEmailVerification, app.localizerFor(r, &user).Locale())
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	otp, err := app.generateOTP(6)
+	err = tx.Commit(r.Context())
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	now := time.Now()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) handlerGetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	user := contextGetAuthenticatedUser(r)
 
-	err = app.db.CreateOTP(r.Context(), database.CreateOTPParams{
-		Code:      otp,
-		Type:      database.OtpTypeEmailVerification,
-		UserID:    user.ID,
-		CreatedAt: now,
-		ExpiresAt: now.Add(time.Minute * 5),
+	err := response.JSON(w, http.StatusOK, dto.NewUser(*user))
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) handlerUpdateCurrentUser(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name      *string             `json:"name"`
+		Locale    *string             `json:"locale"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	user := contextGetAuthenticatedUser(r)
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		input.Validator.CheckField(validator.NotBlank(*input.Name), "name", "name.required", "Name is required")
+		input.Validator.CheckField(validator.MaxRunes(*input.Name, 255), "name", "name.too_long", "Name is too long", "max", 255)
+	}
+
+	if input.Locale != nil && *input.Locale != "" {
+		input.Validator.CheckField(i18n.Supported(*input.Locale), "locale", "locale.unsupported", "Unsupported locale")
+
+		// Store the matching supported locale, so "es-MX" is saved as "es".
+		*input.Locale = i18n.New(*input.Locale).Locale()
+	}
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	updatedUser, err := app.db.UpdateUserProfile(r.Context(), database.UpdateUserProfileParams{
+		Name:   input.Name,
+		Locale: input.Locale,
+		UserID: user.ID,
 	})
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	app.backgroundTask(r, func() error {
-		type EmailData struct {
-			Name string
-			Code string
-		}
+	err = response.JSON(w, http.StatusOK, dto.NewUser(updatedUser))
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
 
-		err = app.mailer.Send(user.Email, EmailData{
-			Name: user.Name,
-			Code: otp,
-		}, "email_confirmation.tmpl")
+// handlerChangePassword sets a new password after checking the current one.
+// Like a password reset, it ends every session, so the client has to log in
+// again afterwards.
+func (app *application) handlerChangePassword(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		CurrentPassword string              `json:"current_password"`
+		NewPassword     string              `json:"new_password"`
+		Validator       validator.Validator `json:"-"`
+	}
 
-		if err != nil {
-			return err
-		}
+	user := contextGetAuthenticatedUser(r)
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	err = checkPassword(&input.Validator, "current_password", input.CurrentPassword, user.HashedPassword)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 
-		return nil
+	validatePassword(&input.Validator, "new_password", input.NewPassword)
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	hashedPassword, err := password.Hash(input.NewPassword)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	tx, err := app.dbPool.Begin(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	qtx := app.db.WithTx(tx)
+
+	err = qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		HashedPassword: hashedPassword,
+		UserID:         user.ID,
 	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = revokeUserSessions(r.Context(), qtx, user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = recordAuditEvent(r, qtx, user.ID, auditPasswordChanged)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = tx.Commit(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (app *application) handlerGetCurrentUser(w http.ResponseWriter, r *http.Request) {
+// handlerRequestEmailChange records the new address as pending and sends an
+// OTP to it. The user's email is only changed once the OTP is confirmed.
+func (app *application) handlerRequestEmailChange(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email     string              `json:"email"`
+		Password  string              `json:"password"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	user := contextGetAuthenticatedUser(r)
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if !app.allowAccount(w, r, "otp_email", app.config.rateLimit.otpEmail, input.Email) {
+		return
+	}
+
+	_, err = app.db.GetUserByEmail(r.Context(), input.Email)
+	notExist := errors.Is(err, pgx.ErrNoRows)
+	if err != nil && !notExist {
+		app.serverError(w, r, err)
+		return
+	}
+
+	input.Validator.CheckField(input.Email != "", "email", "email.required", "Email is required")
+	input.Validator.CheckField(validator.Matches(input.Email, validator.RgxEmail), "email", "email.invalid", "Must be a valid email address")
+	input.Validator.CheckField(input.Email != user.Email, "email", "email.unchanged", "Email is the same as the current one")
+	input.Validator.CheckField(notExist || input.Email == user.Email, "email", "email.taken", "Email is already in use")
+
+	err = checkPassword(&input.Validator, "password", input.Password, user.HashedPassword)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	// The cooldown check, the new pending address and the job that emails a
+	// code to it are committed together. Any code sent to a previous pending
+	// address is deleted, so that it can't confirm this one.
+	tx, err := app.dbPool.Begin(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	qtx := app.db.WithTx(tx)
+
+	err = app.queueOTPEmail(r.Context(), qtx, user.ID, database.OtpTypeEmailChange, app.localizerFor(r, user).Locale())
+	if err != nil {
+		app.otpIssueError(w, r, err)
+		return
+	}
+
+	err = qtx.InvalidateExistingOTP(r.Context(), database.InvalidateExistingOTPParams{
+		UserID: user.ID,
+		Type:   database.OtpTypeEmailChange,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = qtx.SetUserPendingEmail(r.Context(), database.SetUserPendingEmailParams{
+		PendingEmail: &input.Email,
+		UserID:       user.ID,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = tx.Commit(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) handlerConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code      string              `json:"code"`
+		Validator validator.Validator `json:"-"`
+	}
+
 	user := contextGetAuthenticatedUser(r)
 
-	err := response.JSON(w, http.StatusOK, user)
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Validator.CheckField(input.Code != "", "code", "code.required", "Code is required")
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	if user.PendingEmail == nil {
+		app.badRequest(w, r, errNoEmailChangePending)
+		return
+	}
+
+	// The address may have been taken by another account since the change
+	// was requested.
+	_, err = app.db.GetUserByEmail(r.Context(), *user.PendingEmail)
+	notExist := errors.Is(err, pgx.ErrNoRows)
+	if err != nil && !notExist {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !notExist {
+		input.Validator.AddFieldError("email", "email.taken", "Email is already in use")
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	if !app.checkOTP(w, r, user.ID, database.OtpTypeEmailChange, input.Code) {
+		return
+	}
+
+	tx, err := app.dbPool.Begin(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	qtx := app.db.WithTx(tx)
+
+	updatedUser, err := qtx.ConfirmUserEmailChange(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = recordAuditEvent(r, qtx, user.ID, auditEmailChanged)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = tx.Commit(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, dto.NewUser(updatedUser))
 	if err != nil {
 		app.serverError(w, r, err)
 	}
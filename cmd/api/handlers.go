@@ -17,6 +17,20 @@ func (app *application) status(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (app *application) jwks(w http.ResponseWriter, r *http.Request) {
+	headers := make(http.Header)
+	headers.Set("Cache-Control", "public, max-age=300")
+
+	data := map[string]any{
+		"keys": app.jwtKeys.JWKS(),
+	}
+
+	err := response.JSONWithHeaders(w, http.StatusOK, data, headers)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
 func (app *application) protected(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("This is a protected handler"))
 }
@@ -1,52 +1,71 @@
 package main
 
 import (
-	"crypto/rand"
-	"fmt"
-	"math/big"
 	"net/http"
+	"strconv"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+	"github.com/jcarloasilo/golang-rest-template/internal/i18n"
+	"github.com/jcarloasilo/golang-rest-template/internal/password"
+	"github.com/jcarloasilo/golang-rest-template/internal/validator"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
-func (app *application) newEmailData() map[string]any {
-	data := map[string]any{
-		"BaseURL": app.config.baseURL,
+func readUUIDParam(r *http.Request, name string) (uuid.UUID, error) {
+	return uuid.Parse(chi.URLParam(r, name))
+}
+
+// readBoolQuery parses an optional boolean query string value, returning nil
+// when it is empty and adding a field error under key when it is malformed.
+func readBoolQuery(v *validator.Validator, value, key string) *bool {
+	if value == "" {
+		return nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		v.AddFieldError(key, "value.not_boolean", "Must be a boolean value")
+		return nil
 	}
 
-	return data
+	return &b
 }
 
-func (app *application) backgroundTask(r *http.Request, fn func() error) {
-	app.wg.Add(1)
+func validatePassword(v *validator.Validator, key, plaintextPassword string) {
+	v.CheckField(plaintextPassword != "", key, "password.required", "Password is required")
+	v.CheckField(len(plaintextPassword) >= 8, key, "password.too_short", "Password is too short", "min", 8)
+	v.CheckField(len(plaintextPassword) <= 72, key, "password.too_long", "Password is too long", "max", 72)
+	v.CheckField(validator.NotIn(plaintextPassword, password.CommonPasswords...), key, "password.too_common", "Password is too common")
+}
 
-	go func() {
-		defer app.wg.Done()
+// checkPassword adds a field error under key unless plaintextPassword matches
+// the user's current password.
+func checkPassword(v *validator.Validator, key, plaintextPassword, hashedPassword string) error {
+	passwordMatches, err := password.Matches(plaintextPassword, hashedPassword)
+	if err != nil {
+		return err
+	}
 
-		defer func() {
-			err := recover()
-			if err != nil {
-				app.reportServerError(r, fmt.Errorf("%s", err))
-			}
-		}()
+	v.CheckField(plaintextPassword != "", key, "password.required", "Password is required")
+	v.CheckField(passwordMatches, key, "password.incorrect", "Password is incorrect")
 
-		err := fn()
-		if err != nil {
-			app.reportServerError(r, err)
-		}
-	}()
+	return nil
 }
 
-func (app *application) generateOTP(length int) (string, error) {
-	const charSet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	charSetLen := len(charSet)
-	otp := make([]byte, length)
-
-	for i := 0; i < length; i++ {
-		randomIndex, err := rand.Int(rand.Reader, big.NewInt(int64(charSetLen)))
-		if err != nil {
-			return "", err // Return error if random number generation fails
-		}
-		otp[i] = charSet[randomIndex.Int64()]
+// localizerFor picks the language to talk to user in: their saved locale if
+// they have one, and otherwise the request's Accept-Language header.
+func (app *application) localizerFor(r *http.Request, user *database.User) i18n.Localizer {
+	var locale string
+	if user != nil {
+		locale = user.Locale
 	}
 
-	return string(otp), nil
+	return i18n.New(locale, r.Header.Get("Accept-Language"))
+}
+
+// localizer returns the localizer for the authenticated user, if any.
+func (app *application) localizer(r *http.Request) i18n.Localizer {
+	return app.localizerFor(r, contextGetAuthenticatedUser(r))
 }
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/jobs"
+	"github.com/jcarloasilo/golang-rest-template/internal/mailer"
+)
+
+const (
+	jobSendEmail    = "send_email"
+	jobSendOTPEmail = "send_otp_email"
+)
+
+type sendEmailArgs struct {
+	Recipient string         `json:"recipient"`
+	Message   mailer.Message `json:"message"`
+}
+
+// registerJobs sets the handler of every kind of job. Add new kinds here.
+func (app *application) registerJobs() {
+	jobs.Register(app.jobs, jobSendEmail, app.handleSendEmail)
+	jobs.Register(app.jobs, jobSendOTPEmail, app.handleSendOTPEmail)
+}
+
+func (app *application) handleSendEmail(ctx context.Context, args sendEmailArgs) error {
+	return app.mailer.SendMessage(args.Recipient, args.Message)
+}
+
+// queueEmail renders an email and enqueues a job to send it. Rendering here
+// means template errors are reported to the caller, and the job only needs
+// to store the finished message. The message is kept in the jobs table until
+// it is sent, so emails carrying secrets such as OTP codes must be sent with
+// queueOTPEmail instead.
+func (app *application) queueEmail(ctx context.Context, recipient, locale string, data any, opts jobs.EnqueueOptions, name string) error {
+	msg, err := app.mailer.Render(locale, data, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = app.jobs.Enqueue(ctx, jobSendEmail, sendEmailArgs{
+		Recipient: recipient,
+		Message:   msg,
+	}, opts)
+	return err
+}
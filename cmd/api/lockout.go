@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+	"github.com/jcarloasilo/golang-rest-template/internal/jobs"
+)
+
+// lockoutDuration returns how long to lock an account for once it has had
+// failedAttempts failed logins in a row, or zero if it shouldn't be locked.
+// The account is locked after every threshold failures, for twice as long
+// as the previous time, up to the maximum duration.
+func (app *application) lockoutDuration(failedAttempts int32) time.Duration {
+	threshold := int32(app.config.lockout.threshold)
+	if threshold <= 0 || failedAttempts == 0 || failedAttempts%threshold != 0 {
+		return 0
+	}
+
+	d := app.config.lockout.duration
+	for i := int32(1); i < failedAttempts/threshold && d < app.config.lockout.maxDuration; i++ {
+		d *= 2
+	}
+
+	return min(d, app.config.lockout.maxDuration)
+}
+
+// recordFailedLogin counts a failed password or second-factor check against
+// the user, and returns the number of failures in a row. When that locks the
+// account, it records an audit event and emails the user.
+func (app *application) recordFailedLogin(r *http.Request, user database.User) (int32, error) {
+	failedAttempts, err := app.db.RecordFailedLogin(r.Context(), user.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	d := app.lockoutDuration(failedAttempts)
+	if d == 0 {
+		return failedAttempts, nil
+	}
+
+	lockedUntil := time.Now().Add(d)
+
+	err = app.db.LockUser(r.Context(), database.LockUserParams{
+		LockedUntil: lockedUntil,
+		UserID:      user.ID,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordAuditEvent(r, app.db, user.ID, auditAccountLocked)
+	if err != nil {
+		return 0, err
+	}
+
+	locale := app.localizerFor(r, &user).Locale()
+
+	type EmailData struct {
+		Name        string
+		LockedUntil time.Time
+	}
+
+	// One email per lockout is enough if failed logins race.
+	err = app.queueEmail(r.Context(), user.Email, locale, EmailData{
+		Name:        user.Name,
+		LockedUntil: lockedUntil,
+	}, jobs.EnqueueOptions{
+		UniqueKey: "account_locked:" + user.ID.String() + ":" + lockedUntil.Format(time.RFC3339),
+	}, "account_locked.tmpl")
+	if err != nil {
+		return 0, err
+	}
+
+	return failedAttempts, nil
+}
+
+// resetFailedLogins clears the user's count of failed logins. It is only
+// called once a login has completed, including any second factor, so that
+// wrong second-factor codes keep counting towards the lockout across MFA
+// tokens.
+func (app *application) resetFailedLogins(r *http.Request, user database.User) error {
+	if user.FailedLoginAttempts == 0 {
+		return nil
+	}
+
+	return app.db.ResetFailedLogins(r.Context(), user.ID)
+}
+
+func userLocked(user database.User) bool {
+	return user.LockedUntil != nil && user.LockedUntil.After(time.Now())
+}
@@ -1,16 +1,23 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"log/slog"
 	"os"
 	"runtime/debug"
-	"sync"
+	"time"
 
 	"github.com/jcarloasilo/golang-rest-template/internal/database"
 	"github.com/jcarloasilo/golang-rest-template/internal/env"
+	"github.com/jcarloasilo/golang-rest-template/internal/jobs"
+	"github.com/jcarloasilo/golang-rest-template/internal/jwtkeys"
+	"github.com/jcarloasilo/golang-rest-template/internal/mailer"
+	"github.com/jcarloasilo/golang-rest-template/internal/otp"
+	"github.com/jcarloasilo/golang-rest-template/internal/ratelimit"
+	"github.com/jcarloasilo/golang-rest-template/internal/revocation"
 	"github.com/jcarloasilo/golang-rest-template/internal/smtp"
 	"github.com/jcarloasilo/golang-rest-template/internal/version"
 
@@ -32,13 +39,11 @@ func main() {
 }
 
 type config struct {
-	baseURL   string
-	httpPort  int
-	basicAuth struct {
-		username       string
-		hashedPassword string
-	}
-	cookie struct {
+	baseURL        string
+	httpPort       int
+	problemDetails bool
+	privacyMode    bool
+	cookie         struct {
 		secretKey string
 	}
 	db struct {
@@ -50,8 +55,49 @@ type config struct {
 		schema   string
 	}
 	jwt struct {
+		secretKey              string
+		signingKeyFile         string
+		verificationKeyFiles   []string
+		accessTokenTTL         time.Duration
+		refreshTokenTTL        time.Duration
+		revocationSyncInterval time.Duration
+		embedRoles             bool
+	}
+	totp struct {
+		issuer string
+	}
+	otp struct {
 		secretKey string
 	}
+	rateLimit struct {
+		store             string
+		login             ratelimit.Limit
+		loginAccount      ratelimit.Limit
+		register          ratelimit.Limit
+		passwordReset     ratelimit.Limit
+		emailConfirmation ratelimit.Limit
+		otpEmail          ratelimit.Limit
+	}
+	lockout struct {
+		threshold   int
+		duration    time.Duration
+		maxDuration time.Duration
+	}
+	accountDeletion struct {
+		gracePeriod   time.Duration
+		purgeInterval time.Duration
+	}
+	jobs struct {
+		workers      int
+		pollInterval time.Duration
+		timeout      time.Duration
+		maxAttempts  int
+	}
+	mail struct {
+		driver   string
+		fileDir  string
+		previews bool
+	}
 	smtp struct {
 		host     string
 		port     int
@@ -62,12 +108,16 @@ type config struct {
 }
 
 type application struct {
-	config config
-	db     *database.Queries
-	dbPool *pgxpool.Pool
-	logger *slog.Logger
-	mailer *smtp.Mailer
-	wg     sync.WaitGroup
+	config        config
+	db            *database.Queries
+	dbPool        *pgxpool.Pool
+	jobs          *jobs.Queue
+	jwtKeys       *jwtkeys.KeySet
+	logger        *slog.Logger
+	mailer        mailer.Mailer
+	otps          *otp.Service
+	rateLimiter   ratelimit.Store
+	revokedTokens *revocation.List
 }
 
 func run(logger *slog.Logger) error {
@@ -75,11 +125,17 @@ func run(logger *slog.Logger) error {
 
 	cfg.baseURL = env.GetString("BASE_URL", "http://localhost:8080")
 	cfg.httpPort = env.GetInt("HTTP_PORT", 8080)
+	cfg.problemDetails = env.GetBool("PROBLEM_DETAILS", false)
+	cfg.privacyMode = env.GetBool("PRIVACY_MODE", false)
 
-	cfg.basicAuth.username = env.GetString("BASIC_AUTH_USERNAME", "admin")
-	cfg.basicAuth.hashedPassword = env.GetString("BASIC_AUTH_HASHED_PASSWORD", "$2a$10$jRb2qniNcoCyQM23T59RfeEQUbgdAXfR6S0scynmKfJa5Gj3arGJa")
 	cfg.cookie.secretKey = env.GetString("COOKIE_SECRET_KEY", "daapb3ukst43vpjsxf67ehomnlulacr3")
 	cfg.jwt.secretKey = env.GetString("JWT_SECRET_KEY", "2sbhpt3ckvj5i5urt727fmeugwud7i3r")
+	cfg.jwt.signingKeyFile = env.GetString("JWT_SIGNING_KEY_FILE", "")
+	cfg.jwt.verificationKeyFiles = env.GetStringSlice("JWT_VERIFICATION_KEY_FILES", nil)
+	cfg.jwt.accessTokenTTL = env.GetDuration("JWT_ACCESS_TOKEN_TTL", 24*time.Hour)
+	cfg.jwt.refreshTokenTTL = env.GetDuration("JWT_REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	cfg.jwt.revocationSyncInterval = env.GetDuration("JWT_REVOCATION_SYNC_INTERVAL", 30*time.Second)
+	cfg.jwt.embedRoles = env.GetBool("JWT_EMBED_ROLES", false)
 
 	cfg.db.database = env.GetString("DB_DATABASE", "db")
 	cfg.db.password = env.GetString("DB_PASSWORD", "pass")
@@ -88,6 +144,47 @@ func run(logger *slog.Logger) error {
 	cfg.db.host = env.GetString("DB_HOST", "localhost")
 	cfg.db.schema = env.GetString("DB_SCHEMA", "public")
 
+	cfg.totp.issuer = env.GetString("TOTP_ISSUER", "golang-rest-template")
+
+	cfg.otp.secretKey = env.GetString("OTP_SECRET_KEY", "q4xlwmz6fkh2vdn3s7ejt5ycgbpa8ru9")
+
+	cfg.rateLimit.store = env.GetString("RATE_LIMIT_STORE", "memory")
+
+	for _, l := range []struct {
+		limit        *ratelimit.Limit
+		key          string
+		defaultValue string
+	}{
+		{&cfg.rateLimit.login, "RATE_LIMIT_LOGIN", "10/1m"},
+		{&cfg.rateLimit.loginAccount, "RATE_LIMIT_LOGIN_ACCOUNT", "5/5m"},
+		{&cfg.rateLimit.register, "RATE_LIMIT_REGISTER", "5/1h"},
+		{&cfg.rateLimit.passwordReset, "RATE_LIMIT_PASSWORD_RESET", "5/15m"},
+		{&cfg.rateLimit.emailConfirmation, "RATE_LIMIT_EMAIL_CONFIRMATION", "5/15m"},
+		{&cfg.rateLimit.otpEmail, "RATE_LIMIT_OTP_EMAIL", "3/15m"},
+	} {
+		limit, err := ratelimit.ParseLimit(env.GetString(l.key, l.defaultValue))
+		if err != nil {
+			return fmt.Errorf("%s: %w", l.key, err)
+		}
+		*l.limit = limit
+	}
+
+	cfg.lockout.threshold = env.GetInt("LOGIN_LOCKOUT_THRESHOLD", 5)
+	cfg.lockout.duration = env.GetDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+	cfg.lockout.maxDuration = env.GetDuration("LOGIN_LOCKOUT_MAX_DURATION", 24*time.Hour)
+
+	cfg.accountDeletion.gracePeriod = env.GetDuration("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour)
+	cfg.accountDeletion.purgeInterval = env.GetDuration("ACCOUNT_DELETION_PURGE_INTERVAL", time.Hour)
+
+	cfg.jobs.workers = env.GetInt("JOBS_WORKERS", 4)
+	cfg.jobs.pollInterval = env.GetDuration("JOBS_POLL_INTERVAL", time.Second)
+	cfg.jobs.timeout = env.GetDuration("JOBS_TIMEOUT", 5*time.Minute)
+	cfg.jobs.maxAttempts = env.GetInt("JOBS_MAX_ATTEMPTS", 10)
+
+	cfg.mail.driver = env.GetString("MAIL_DRIVER", "smtp")
+	cfg.mail.fileDir = env.GetString("MAIL_FILE_DIR", "tmp/emails")
+	cfg.mail.previews = env.GetBool("MAIL_PREVIEWS", false)
+
 	cfg.smtp.host = env.GetString("SMTP_HOST", "example.smtp.host")
 	cfg.smtp.port = env.GetInt("SMTP_PORT", 25)
 	cfg.smtp.username = env.GetString("SMTP_USERNAME", "example_username")
@@ -113,18 +210,89 @@ func run(logger *slog.Logger) error {
 
 	db := database.New(dbPool)
 
-	mailer, err := smtp.NewMailer(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.from)
+	jwtKeys := jwtkeys.NewHMAC([]byte(cfg.jwt.secretKey))
+	if cfg.jwt.signingKeyFile != "" {
+		jwtKeys, err = jwtkeys.Load(cfg.jwt.signingKeyFile, cfg.jwt.verificationKeyFiles...)
+		if err != nil {
+			return err
+		}
+	}
+
+	var mailTransport mailer.Transport
+	switch cfg.mail.driver {
+	case "smtp":
+		mailTransport, err = smtp.NewTransport(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.from)
+	case "file":
+		mailTransport, err = mailer.NewFileTransport(cfg.mail.fileDir, cfg.smtp.from)
+	case "log":
+		mailTransport = mailer.NewLogTransport(logger)
+	default:
+		err = fmt.Errorf("MAIL_DRIVER: unknown driver %q", cfg.mail.driver)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("mailer configured with %s driver", cfg.mail.driver)
+
+	mail, err := mailer.New(mailTransport)
 	if err != nil {
 		return err
 	}
-	log.Println("mailer connection established")
+
+	var rateLimiter ratelimit.Store
+	var rateLimitBuckets *ratelimit.PostgresStore
+	switch cfg.rateLimit.store {
+	case "memory":
+		rateLimiter = ratelimit.NewMemoryStore()
+	case "postgres":
+		rateLimitBuckets = ratelimit.NewPostgresStore(db)
+		rateLimiter = rateLimitBuckets
+	default:
+		return fmt.Errorf("RATE_LIMIT_STORE: unknown store %q", cfg.rateLimit.store)
+	}
 
 	app := &application{
 		config: cfg,
 		db:     db,
 		dbPool: dbPool,
-		logger: logger,
-		mailer: mailer,
+		jobs: jobs.NewQueue(db, logger, jobs.Options{
+			Workers:      cfg.jobs.workers,
+			PollInterval: cfg.jobs.pollInterval,
+			Timeout:      cfg.jobs.timeout,
+			MaxAttempts:  cfg.jobs.maxAttempts,
+		}),
+		jwtKeys:       jwtKeys,
+		logger:        logger,
+		mailer:        mail,
+		otps:          otp.NewService(otp.NewPostgresStore(db), cfg.otp.secretKey, otpPolicies),
+		rateLimiter:   rateLimiter,
+		revokedTokens: revocation.NewList(),
+	}
+
+	// Fail fast on a broken email template rather than when it is first sent.
+	err = mailer.CheckTemplates(app.mailer)
+	if err != nil {
+		return err
+	}
+
+	err = app.syncRevokedTokens(context.Background())
+	if err != nil {
+		return err
+	}
+
+	err = app.hashPlaintextOTPs(context.Background())
+	if err != nil {
+		return err
+	}
+
+	app.registerJobs()
+	app.jobs.Start()
+
+	go app.runRevokedTokensSync(cfg.jwt.revocationSyncInterval)
+	go app.runDeletedUsersPurge(cfg.accountDeletion.purgeInterval)
+
+	if rateLimitBuckets != nil {
+		go app.runRateLimitCleanup(rateLimitBuckets, time.Hour)
 	}
 
 	return app.serveHTTP()
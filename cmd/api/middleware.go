@@ -5,17 +5,31 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
-	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jcarloasilo/golang-rest-template/internal/response"
-	"github.com/pascaldekloe/jwt"
 	"github.com/tomasen/realip"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// requestID tags every request with an ID, reusing the client's X-Request-Id
+// header when it looks sane, and echoes it back in the response.
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-Id")
+		if requestID == "" || len(requestID) > 128 || strings.ContainsFunc(requestID, func(c rune) bool { return c < 0x21 || c > 0x7e }) {
+			requestID = uuid.NewString()
+		}
+
+		w.Header().Set("X-Request-Id", requestID)
+		r = contextSetRequestID(r, requestID)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
@@ -42,7 +56,7 @@ func (app *application) logAccess(next http.Handler) http.Handler {
 		)
 
 		userAttrs := slog.Group("user", "ip", ip)
-		requestAttrs := slog.Group("request", "method", method, "url", url, "proto", proto)
+		requestAttrs := slog.Group("request", "id", contextGetRequestID(r), "method", method, "url", url, "proto", proto)
 		responseAttrs := slog.Group("response", "status", mw.StatusCode, "size", mw.BytesCount)
 
 		app.logger.Info("access", userAttrs, requestAttrs, responseAttrs)
@@ -61,27 +75,12 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			if len(headerParts) == 2 && headerParts[0] == "Bearer" {
 				token := headerParts[1]
 
-				claims, err := jwt.HMACCheck([]byte(token), []byte(app.config.jwt.secretKey))
+				claims, err := app.parseToken(token, app.config.baseURL)
 				if err != nil {
 					app.invalidAuthenticationToken(w, r)
 					return
 				}
 
-				if !claims.Valid(time.Now()) {
-					app.invalidAuthenticationToken(w, r)
-					return
-				}
-
-				if claims.Issuer != app.config.baseURL {
-					app.invalidAuthenticationToken(w, r)
-					return
-				}
-
-				if !claims.AcceptAudience(app.config.baseURL) {
-					app.invalidAuthenticationToken(w, r)
-					return
-				}
-
 				userID, err := uuid.Parse(claims.Subject)
 				if err != nil {
 					app.serverError(w, r, err)
@@ -97,7 +96,13 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 						return
 					}
 				} else {
+					if sessionRevoked(user, claims) || user.DisabledAt != nil {
+						app.invalidAuthenticationToken(w, r)
+						return
+					}
+
 					r = contextSetAuthenticatedUser(r, &user)
+					r = contextSetAuthenticationClaims(r, claims)
 				}
 			}
 		}
@@ -137,29 +142,31 @@ func (app *application) requireVerifiedUser(next http.Handler) http.Handler {
 	})
 }
 
-func (app *application) requireBasicAuthentication(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		username, plaintextPassword, ok := r.BasicAuth()
-		if !ok {
-			app.basicAuthenticationRequired(w, r)
-			return
-		}
+// requirePermission only lets through authenticated users holding one of
+// their roles grants the given permission. Permissions are always read from
+// the database, so role changes take effect immediately.
+func (app *application) requirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authenticatedUser := contextGetAuthenticatedUser(r)
+
+			if authenticatedUser == nil {
+				app.authenticationRequired(w, r)
+				return
+			}
 
-		if app.config.basicAuth.username != username {
-			app.basicAuthenticationRequired(w, r)
-			return
-		}
+			permissions, err := app.db.GetUserPermissionNames(r.Context(), authenticatedUser.ID)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
 
-		err := bcrypt.CompareHashAndPassword([]byte(app.config.basicAuth.hashedPassword), []byte(plaintextPassword))
-		switch {
-		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
-			app.basicAuthenticationRequired(w, r)
-			return
-		case err != nil:
-			app.serverError(w, r, err)
-			return
-		}
+			if !slices.Contains(permissions, permission) {
+				app.notPermitted(w, r)
+				return
+			}
 
-		next.ServeHTTP(w, r)
-	})
+			next.ServeHTTP(w, r)
+		})
+	}
 }
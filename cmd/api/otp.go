@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+	"github.com/jcarloasilo/golang-rest-template/internal/jobs"
+	"github.com/jcarloasilo/golang-rest-template/internal/otp"
+	"github.com/jcarloasilo/golang-rest-template/internal/validator"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// otpPolicies sets the length, alphabet, lifetime, number of attempts and
+// resend cooldown of the OTPs of each type.
+var otpPolicies = map[database.OtpType]otp.Policy{
+	database.OtpTypeEmailVerification: {
+		Length:         6,
+		Alphabet:       otp.AlphaNumeric,
+		TTL:            5 * time.Minute,
+		MaxAttempts:    5,
+		ResendCooldown: time.Minute,
+	},
+	database.OtpTypePasswordReset: {
+		Length:         6,
+		Alphabet:       otp.AlphaNumeric,
+		TTL:            5 * time.Minute,
+		MaxAttempts:    5,
+		ResendCooldown: time.Minute,
+	},
+	database.OtpTypeEmailChange: {
+		Length:         6,
+		Alphabet:       otp.AlphaNumeric,
+		TTL:            5 * time.Minute,
+		MaxAttempts:    5,
+		ResendCooldown: time.Minute,
+	},
+	database.OtpTypeTwoFactorAuth: {
+		Length:         6,
+		Alphabet:       otp.Digits,
+		TTL:            5 * time.Minute,
+		MaxAttempts:    5,
+		ResendCooldown: 30 * time.Second,
+	},
+}
+
+// otpEmailTemplates names the email template that carries the OTPs of each
+// type.
+var otpEmailTemplates = map[database.OtpType]string{
+	database.OtpTypeEmailVerification: "email_confirmation.tmpl",
+	database.OtpTypePasswordReset:     "password_reset.tmpl",
+	database.OtpTypeEmailChange:       "email_change.tmpl",
+	database.OtpTypeTwoFactorAuth:     "two_factor_auth.tmpl",
+}
+
+type sendOTPEmailArgs struct {
+	UserID uuid.UUID        `json:"user_id"`
+	Type   database.OtpType `json:"type"`
+	Locale string           `json:"locale"`
+}
+
+// queueOTPEmail enqueues a job that issues an OTP of the given type to the
+// user and emails it to them. The code is only generated when the job runs,
+// so it is never stored anywhere but as a hash. db can be bound to a
+// transaction, so that the job only exists if the transaction commits. It
+// returns an *otp.CooldownError if the previous code was sent too recently.
+func (app *application) queueOTPEmail(ctx context.Context, db *database.Queries, userID uuid.UUID, otpType database.OtpType, locale string) error {
+	err := app.otps.WithStore(otp.NewPostgresStore(db)).CheckCooldown(ctx, userID, otpType)
+	if err != nil {
+		return err
+	}
+
+	// While a job is waiting, it will send a code that is issued when it
+	// runs, so a second one isn't needed.
+	_, err = app.jobs.EnqueueTx(ctx, db, jobSendOTPEmail, sendOTPEmailArgs{
+		UserID: userID,
+		Type:   otpType,
+		Locale: locale,
+	}, jobs.EnqueueOptions{
+		UniqueKey: "otp_email:" + userID.String() + ":" + string(otpType),
+	})
+	return err
+}
+
+// handleSendOTPEmail issues an OTP and emails it. Email change codes go to
+// the pending address. A retried job issues a fresh code, replacing the one
+// that failed to send.
+func (app *application) handleSendOTPEmail(ctx context.Context, args sendOTPEmailArgs) error {
+	user, err := app.db.GetUser(ctx, args.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	recipient := user.Email
+
+	switch args.Type {
+	case database.OtpTypeEmailVerification:
+		if user.VerifiedAt != nil {
+			return nil
+		}
+	case database.OtpTypeEmailChange:
+		if user.PendingEmail == nil {
+			return nil
+		}
+		recipient = *user.PendingEmail
+	}
+
+	code, err := app.otps.Issue(ctx, user.ID, args.Type)
+	if err != nil {
+		return err
+	}
+
+	type EmailData struct {
+		Name string
+		Code string
+	}
+
+	return app.mailer.Send(recipient, args.Locale, EmailData{
+		Name: user.Name,
+		Code: code,
+	}, otpEmailTemplates[args.Type])
+}
+
+// checkOTP verifies and consumes the user's OTP of the given type and, if
+// the code isn't accepted, sends the matching error response and returns
+// false.
+func (app *application) checkOTP(w http.ResponseWriter, r *http.Request, userID uuid.UUID, otpType database.OtpType, code string) bool {
+	err := app.otps.Verify(r.Context(), userID, otpType, code)
+	if err != nil {
+		app.otpVerifyError(w, r, err)
+		return false
+	}
+
+	return true
+}
+
+// otpVerifyError sends the error response for an error returned by
+// otp.Service.Verify.
+func (app *application) otpVerifyError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, otp.ErrInvalid), errors.Is(err, otp.ErrNotFound):
+		var v validator.Validator
+		v.AddFieldError("code", "code.invalid", "Invalid OTP")
+		app.failedValidation(w, r, v)
+	case errors.Is(err, otp.ErrExpired):
+		app.badRequest(w, r, errOTPExpired)
+	case errors.Is(err, otp.ErrTooManyAttempts):
+		app.badRequest(w, r, errOTPTooManyAttempts)
+	default:
+		app.serverError(w, r, err)
+	}
+}
+
+// isOTPRejected reports whether err means that Verify didn't accept the
+// code, as opposed to failing to check it.
+func isOTPRejected(err error) bool {
+	return errors.Is(err, otp.ErrInvalid) || errors.Is(err, otp.ErrNotFound) ||
+		errors.Is(err, otp.ErrExpired) || errors.Is(err, otp.ErrTooManyAttempts)
+}
+
+// otpIssueError sends the error response for an error returned by
+// queueOTPEmail.
+func (app *application) otpIssueError(w http.ResponseWriter, r *http.Request, err error) {
+	var cooldown *otp.CooldownError
+	if !errors.As(err, &cooldown) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Retry-After", strconv.Itoa(int(math.Ceil(cooldown.RetryAfter.Seconds()))))
+
+	message := "A code was sent recently, please wait before requesting another"
+	app.errorMessage(w, r, http.StatusTooManyRequests, "otp.resend_cooldown", message, headers)
+}
+
+// isOTPCooldown reports whether err is an *otp.CooldownError.
+func isOTPCooldown(err error) bool {
+	var cooldown *otp.CooldownError
+	return errors.As(err, &cooldown)
+}
+
+// hashPlaintextOTPs hashes any codes that were stored in plaintext before
+// codes were hashed at rest, and clears the plaintext.
+func (app *application) hashPlaintextOTPs(ctx context.Context) error {
+	plaintextOTPs, err := app.db.GetPlaintextOTPs(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, plaintextOTP := range plaintextOTPs {
+		err = app.db.SetOTPCodeHash(ctx, database.SetOTPCodeHashParams{
+			CodeHash: app.otps.Hash(*plaintextOTP.Code),
+			ID:       plaintextOTP.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(plaintextOTPs) > 0 {
+		app.logger.Info("hashed plaintext otps", "count", len(plaintextOTPs))
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/ratelimit"
+
+	"github.com/tomasen/realip"
+)
+
+// rateLimitByIP limits requests by client IP address. Routes that use the
+// same name share a bucket.
+func (app *application) rateLimitByIP(name string, limit ratelimit.Limit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !app.allowRequest(w, r, name+":ip:"+realip.FromRequest(r), limit) {
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allowAccount is the per-account counterpart to rateLimitByIP, for use in
+// handlers once they have read the email address from the request body. It
+// sends a 429 response and returns false if the limit has been reached.
+// Requests without an email address are left to the handler's validation,
+// so that they don't all drain one shared bucket.
+func (app *application) allowAccount(w http.ResponseWriter, r *http.Request, name string, limit ratelimit.Limit, email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return true
+	}
+
+	return app.allowRequest(w, r, name+":account:"+email, limit)
+}
+
+func (app *application) allowRequest(w http.ResponseWriter, r *http.Request, key string, limit ratelimit.Limit) bool {
+	if limit.Unlimited() {
+		return true
+	}
+
+	res, err := app.rateLimiter.Take(r.Context(), key, limit)
+	if err != nil {
+		app.serverError(w, r, err)
+		return false
+	}
+
+	if !res.Allowed {
+		app.rateLimitExceeded(w, r, res.RetryAfter)
+		return false
+	}
+
+	return true
+}
+
+func (app *application) runRateLimitCleanup(store *ratelimit.PostgresStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		_, err := store.DeleteExpired(ctx)
+		cancel()
+
+		if err != nil {
+			app.logger.Error("failed to delete expired rate limit buckets", "error", err)
+		}
+	}
+}
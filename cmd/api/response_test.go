@@ -0,0 +1,238 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestResponsesHaveNoDatabaseModels type-checks this package and fails if any
+// call to response.JSON or response.JSONWithHeaders is given data that holds
+// a model from internal/database. Those carry json tags for every column, so
+// handlers must map them to a type from internal/dto first.
+func TestResponsesHaveNoDatabaseModels(t *testing.T) {
+	fset := token.NewFileSet()
+
+	pkgs, err := parser.ParseDir(fset, ".", func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var files []*ast.File
+	for _, file := range pkgs["main"].Files {
+		files = append(files, file)
+	}
+
+	info := &types.Info{
+		Types: map[ast.Expr]types.TypeAndValue{},
+		Defs:  map[*ast.Ident]types.Object{},
+		Uses:  map[*ast.Ident]types.Object{},
+	}
+
+	conf := types.Config{Importer: exportDataImporter(t, fset)}
+
+	_, err = conf.Check("main", fset, files, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &modelFinder{info: info, values: assignedValues(info, files)}
+
+	calls := 0
+
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || !isResponseJSON(info, call) || len(call.Args) < 3 {
+				return true
+			}
+
+			calls++
+
+			if model := f.findInExpr(call.Args[2], map[types.Object]bool{}); model != "" {
+				t.Errorf("%s: response holds the database model %s", fset.Position(call.Pos()), model)
+			}
+
+			return true
+		})
+	}
+
+	if calls == 0 {
+		t.Error("found no calls to response.JSON")
+	}
+}
+
+// exportDataImporter returns an importer that reads the compiled export data
+// of this package's dependencies, as listed by go list.
+func exportDataImporter(t *testing.T, fset *token.FileSet) types.Importer {
+	out, err := exec.Command("go", "list", "-export", "-deps", "-f", "{{.ImportPath}}={{.Export}}", ".").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exports := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		path, export, _ := strings.Cut(line, "=")
+		exports[path] = export
+	}
+
+	return importer.ForCompiler(fset, "gc", func(path string) (io.ReadCloser, error) {
+		return os.Open(exports[path])
+	})
+}
+
+func isResponseJSON(info *types.Info, call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+
+	fn, ok := info.Uses[sel.Sel].(*types.Func)
+	if !ok || fn.Pkg() == nil || !strings.HasSuffix(fn.Pkg().Path(), "/internal/response") {
+		return false
+	}
+
+	return fn.Name() == "JSON" || fn.Name() == "JSONWithHeaders"
+}
+
+// assignedValues maps each variable to the values assigned to it, including
+// values stored in it by index, such as data["user"] = user.
+func assignedValues(info *types.Info, files []*ast.File) map[types.Object][]ast.Expr {
+	values := map[types.Object][]ast.Expr{}
+
+	add := func(lhs, rhs ast.Expr) {
+		if index, ok := lhs.(*ast.IndexExpr); ok {
+			lhs = index.X
+		}
+
+		ident, ok := lhs.(*ast.Ident)
+		if !ok {
+			return
+		}
+
+		obj := info.Defs[ident]
+		if obj == nil {
+			obj = info.Uses[ident]
+		}
+
+		if obj != nil {
+			values[obj] = append(values[obj], rhs)
+		}
+	}
+
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				if len(n.Lhs) == len(n.Rhs) {
+					for i := range n.Lhs {
+						add(n.Lhs[i], n.Rhs[i])
+					}
+				}
+			case *ast.ValueSpec:
+				if len(n.Names) == len(n.Values) {
+					for i := range n.Names {
+						add(n.Names[i], n.Values[i])
+					}
+				}
+			}
+
+			return true
+		})
+	}
+
+	return values
+}
+
+type modelFinder struct {
+	info   *types.Info
+	values map[types.Object][]ast.Expr
+}
+
+// findInExpr checks the static type of expr and, because fields of type any
+// hide what they hold, every value in a composite literal and every value
+// assigned to a variable.
+func (f *modelFinder) findInExpr(expr ast.Expr, seen map[types.Object]bool) string {
+	switch e := expr.(type) {
+	case *ast.CompositeLit:
+		for _, elt := range e.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				elt = kv.Value
+			}
+
+			if model := f.findInExpr(elt, seen); model != "" {
+				return model
+			}
+		}
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return f.findInExpr(e.X, seen)
+		}
+	case *ast.ParenExpr:
+		return f.findInExpr(e.X, seen)
+	case *ast.Ident:
+		if obj := f.info.Uses[e]; obj != nil && !seen[obj] {
+			seen[obj] = true
+
+			for _, value := range f.values[obj] {
+				if model := f.findInExpr(value, seen); model != "" {
+					return model
+				}
+			}
+		}
+	}
+
+	return findDatabaseModel(f.info.TypeOf(expr), map[types.Type]bool{})
+}
+
+// findDatabaseModel returns the name of a struct type from internal/database
+// that typ is or contains, or an empty string.
+func findDatabaseModel(typ types.Type, seen map[types.Type]bool) string {
+	if typ == nil || seen[typ] {
+		return ""
+	}
+	seen[typ] = true
+
+	if named, ok := typ.(*types.Named); ok {
+		pkg := named.Obj().Pkg()
+		if _, isStruct := named.Underlying().(*types.Struct); isStruct && pkg != nil && strings.HasSuffix(pkg.Path(), "/internal/database") {
+			return named.String()
+		}
+	}
+
+	switch typ := typ.Underlying().(type) {
+	case *types.Pointer:
+		return findDatabaseModel(typ.Elem(), seen)
+	case *types.Slice:
+		return findDatabaseModel(typ.Elem(), seen)
+	case *types.Array:
+		return findDatabaseModel(typ.Elem(), seen)
+	case *types.Map:
+		if model := findDatabaseModel(typ.Key(), seen); model != "" {
+			return model
+		}
+		return findDatabaseModel(typ.Elem(), seen)
+	case *types.Struct:
+		for i := 0; i < typ.NumFields(); i++ {
+			if !typ.Field(i).Exported() {
+				continue
+			}
+
+			if model := findDatabaseModel(typ.Field(i).Type(), seen); model != "" {
+				return model
+			}
+		}
+	}
+
+	return ""
+}
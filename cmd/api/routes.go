@@ -17,44 +17,91 @@ func (app *application) routes() http.Handler {
 		// AllowedOrigins:   []string{"https://foo.com"}, // Use this to allow specific origin hosts
 		AllowedOrigins: []string{"https://*", "http://*"},
 		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", "Retry-After", "X-Request-Id"},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
 
+	mux.Use(app.requestID)
 	mux.Use(app.logAccess)
 	mux.Use(app.recoverPanic)
 	mux.Use(app.authenticate)
 
 	mux.Get("/status", app.status)
+	mux.Get("/.well-known/jwks.json", app.jwks)
 
-	mux.Post("/login", app.handlerLogin)
+	loginLimit := app.rateLimitByIP("login", app.config.rateLimit.login)
+	passwordResetLimit := app.rateLimitByIP("password_reset", app.config.rateLimit.passwordReset)
 
-	mux.Post("/register", app.handlerCreateUser)
+	mux.With(loginLimit).Post("/login", app.handlerLogin)
+	mux.With(loginLimit).Post("/login/2fa", app.handlerLoginTwoFactor)
+	mux.Post("/auth/refresh", app.handlerRefreshToken)
+
+	mux.With(app.rateLimitByIP("register", app.config.rateLimit.register)).Post("/register", app.handlerCreateUser)
+
+	mux.With(passwordResetLimit).Post("/password-reset/request", app.handlerPasswordResetRequest)
+	mux.With(passwordResetLimit).Post("/password-reset/confirm", app.handlerPasswordResetConfirm)
 
 	mux.Group(func(mux chi.Router) {
 		mux.Use(app.requireAuthenticatedUser)
 
 		mux.Get("/protected", app.protected)
 
+		mux.Post("/logout", app.handlerLogout)
+		mux.Post("/logout/all", app.handlerLogoutAll)
+
 		mux.Get("/users/me", app.handlerGetCurrentUser)
+		mux.Patch("/users/me", app.handlerUpdateCurrentUser)
+		mux.Post("/users/me/password", app.handlerChangePassword)
+		mux.Post("/users/me/email", app.handlerRequestEmailChange)
+		mux.Post("/users/me/email/confirm", app.handlerConfirmEmailChange)
+		mux.Delete("/users/me", app.handlerDeleteCurrentUser)
+		mux.Get("/users/me/export", app.handlerExportCurrentUser)
 
 		mux.Post("/email-confirmation", app.handlerEmailConfirmation)
-		mux.Post("/email-confirmation/request", app.handlerNewEmailConfirmation)
+		mux.With(app.rateLimitByIP("email_confirmation", app.config.rateLimit.emailConfirmation)).Post("/email-confirmation/request", app.handlerNewEmailConfirmation)
 	})
 
 	mux.Group(func(mux chi.Router) {
 		mux.Use(app.requireVerifiedUser)
 
 		mux.Get("/verified-protected", app.verified)
-	})
 
-	mux.Group(func(mux chi.Router) {
-		mux.Use(app.requireBasicAuthentication)
+		mux.Post("/users/me/2fa/enable", app.handlerEnableTwoFactor)
+		mux.Post("/users/me/2fa/disable", app.handlerDisableTwoFactor)
+
+		mux.Post("/users/me/totp", app.handlerEnrollTOTP)
+		mux.Post("/users/me/totp/confirm", app.handlerConfirmTOTP)
+		mux.Delete("/users/me/totp", app.handlerDisableTOTP)
+		mux.Post("/users/me/totp/recovery-codes", app.handlerRegenerateRecoveryCodes)
+	})
 
-		mux.Get("/basic-auth-protected", app.protected)
+	mux.Route("/admin", func(mux chi.Router) {
+		mux.With(app.requirePermission("roles:read")).Get("/roles", app.handlerListRoles)
+		mux.With(app.requirePermission("roles:read")).Get("/users/{id}/roles", app.handlerGetUserRoles)
+		mux.With(app.requirePermission("roles:write")).Put("/users/{id}/roles/{role}", app.handlerAssignUserRole)
+		mux.With(app.requirePermission("roles:write")).Delete("/users/{id}/roles/{role}", app.handlerRemoveUserRole)
+
+		mux.With(app.requirePermission("users:read")).Get("/users", app.handlerAdminListUsers)
+		mux.With(app.requirePermission("users:read")).Get("/users/{id}", app.handlerAdminGetUser)
+		mux.With(app.requirePermission("users:write")).Patch("/users/{id}", app.handlerAdminUpdateUser)
+		mux.With(app.requirePermission("users:write")).Delete("/users/{id}", app.handlerAdminDeleteUser)
+		mux.With(app.requirePermission("users:write")).Post("/users/{id}/disable", app.handlerAdminDisableUser)
+		mux.With(app.requirePermission("users:write")).Post("/users/{id}/enable", app.handlerAdminEnableUser)
+		mux.With(app.requirePermission("users:write")).Post("/users/{id}/unlock", app.handlerAdminUnlockUser)
+		mux.With(app.requirePermission("users:write")).Post("/users/{id}/verify", app.handlerAdminVerifyUser)
+		mux.With(app.requirePermission("users:write")).Post("/users/{id}/verification-email", app.handlerAdminResendVerification)
+
+		mux.With(app.requirePermission("jobs:read")).Get("/jobs", app.handlerAdminGetJobStats)
+		mux.With(app.requirePermission("jobs:read")).Get("/jobs/failed", app.handlerAdminListFailedJobs)
+		mux.With(app.requirePermission("jobs:write")).Post("/jobs/{id}/retry", app.handlerAdminRetryJob)
+
+		if app.config.mail.previews {
+			mux.With(app.requirePermission("emails:read")).Get("/email-templates", app.handlerAdminListEmailTemplates)
+			mux.With(app.requirePermission("emails:read")).Get("/email-templates/{name}", app.handlerAdminPreviewEmailTemplate)
+		}
 	})
 
 	return mux
@@ -39,7 +39,12 @@ func (app *application) serveHTTP() error {
 		ctx, cancel := context.WithTimeout(context.Background(), defaultShutdownPeriod)
 		defer cancel()
 
-		shutdownErrorChan <- srv.Shutdown(ctx)
+		err := srv.Shutdown(ctx)
+
+		// Let running jobs finish, now that no request can enqueue more. This
+		// is done even if the server didn't shut down cleanly, so that jobs
+		// aren't cut off when the process exits.
+		shutdownErrorChan <- errors.Join(err, app.jobs.Shutdown(ctx))
 	}()
 
 	app.logger.Info("starting server", slog.Group("server", "addr", srv.Addr))
@@ -56,6 +61,5 @@ func (app *application) serveHTTP() error {
 
 	app.logger.Info("stopped server", slog.Group("server", "addr", srv.Addr))
 
-	app.wg.Wait()
 	return nil
 }
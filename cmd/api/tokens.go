@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+
+	"github.com/google/uuid"
+	"github.com/pascaldekloe/jwt"
+)
+
+const (
+	mfaTokenTTL = 5 * time.Minute
+
+	// mfaMaxAttempts is the number of wrong second-factor codes in a row
+	// after which each further wrong code revokes the MFA token it was sent
+	// with.
+	mfaMaxAttempts = 5
+)
+
+var errInvalidToken = errors.New("invalid token")
+
+func (app *application) newAuthenticationToken(ctx context.Context, userID uuid.UUID) (string, time.Time, error) {
+	expiry := time.Now().Add(app.config.jwt.accessTokenTTL)
+
+	// Roles are only embedded for the benefit of other services reading the
+	// token; this application always checks permissions against the database.
+	var extra map[string]any
+	if app.config.jwt.embedRoles {
+		roles, err := app.db.GetUserRoleNames(ctx, userID)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+
+		if roles == nil {
+			roles = []string{}
+		}
+
+		extra = map[string]any{"roles": roles}
+	}
+
+	token, err := app.signToken(userID, app.config.baseURL, expiry, extra)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiry, nil
+}
+
+// newMFAToken returns a token proving that the user has already passed the
+// password check. It is only accepted by the second login step and cannot be
+// used to authenticate other requests.
+func (app *application) newMFAToken(userID uuid.UUID) (string, time.Time, error) {
+	expiry := time.Now().Add(mfaTokenTTL)
+
+	token, err := app.signToken(userID, app.mfaAudience(), expiry, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiry, nil
+}
+
+func (app *application) mfaAudience() string {
+	return app.config.baseURL + "/login/2fa"
+}
+
+func (app *application) signToken(userID uuid.UUID, audience string, expiry time.Time, extra map[string]any) (string, error) {
+	var claims jwt.Claims
+	claims.Set = extra
+	claims.ID = uuid.New().String()
+	claims.Subject = userID.String()
+
+	claims.Issued = jwt.NewNumericTime(time.Now())
+	claims.NotBefore = jwt.NewNumericTime(time.Now())
+	claims.Expires = jwt.NewNumericTime(expiry)
+
+	claims.Issuer = app.config.baseURL
+	claims.Audiences = []string{audience}
+
+	jwtBytes, err := app.jwtKeys.Sign(&claims)
+	if err != nil {
+		return "", err
+	}
+
+	return string(jwtBytes), nil
+}
+
+// parseToken checks the signature and registered claims of a token issued by
+// this application for the given audience, and that it has not been revoked.
+func (app *application) parseToken(token, audience string) (*jwt.Claims, error) {
+	claims, err := app.jwtKeys.Check([]byte(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if !claims.Valid(time.Now()) {
+		return nil, errInvalidToken
+	}
+
+	if claims.Issuer != app.config.baseURL {
+		return nil, errInvalidToken
+	}
+
+	if !claims.AcceptAudience(audience) {
+		return nil, errInvalidToken
+	}
+
+	if claims.ID != "" && app.revokedTokens.Contains(claims.ID) {
+		return nil, errInvalidToken
+	}
+
+	return claims, nil
+}
+
+// newRefreshToken stores a new refresh token belonging to the given token
+// family and returns the plaintext value, which is never persisted.
+func (app *application) newRefreshToken(ctx context.Context, db *database.Queries, userID, familyID uuid.UUID) (string, database.RefreshToken, error) {
+	randomBytes := make([]byte, 32)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", database.RefreshToken{}, err
+	}
+
+	plaintext := base64.RawURLEncoding.EncodeToString(randomBytes)
+
+	refreshToken, err := db.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashRefreshToken(plaintext),
+		ExpiresAt: time.Now().Add(app.config.jwt.refreshTokenTTL),
+	})
+	if err != nil {
+		return "", database.RefreshToken{}, err
+	}
+
+	return plaintext, refreshToken, nil
+}
+
+func hashRefreshToken(plaintext string) []byte {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hash[:]
+}
+
+func (app *application) revokeAuthenticationToken(ctx context.Context, userID uuid.UUID, claims *jwt.Claims) error {
+	if claims.ID == "" || claims.Expires == nil {
+		return nil
+	}
+
+	err := app.db.RevokeToken(ctx, database.RevokeTokenParams{
+		Jti:       claims.ID,
+		UserID:    userID,
+		ExpiresAt: claims.Expires.Time(),
+	})
+	if err != nil {
+		return err
+	}
+
+	app.revokedTokens.Add(claims.ID, claims.Expires.Time())
+	return nil
+}
+
+// sessionRevoked reports whether the token was issued before the user last
+// revoked all of their sessions.
+func sessionRevoked(user database.User, claims *jwt.Claims) bool {
+	if user.TokensRevokedAt == nil {
+		return false
+	}
+
+	return claims.Issued == nil || !claims.Issued.Time().After(*user.TokensRevokedAt)
+}
+
+// revokeUserSessions invalidates every access and refresh token issued to the
+// user so far.
+func revokeUserSessions(ctx context.Context, db *database.Queries, userID uuid.UUID) error {
+	err := db.RevokeUserTokens(ctx, database.RevokeUserTokensParams{
+		TokensRevokedAt: time.Now(),
+		UserID:          userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return db.RevokeUserRefreshTokens(ctx, userID)
+}
+
+// syncRevokedTokens loads revocations made by other instances into the
+// in-memory list and clears out rows for tokens that have since expired.
+func (app *application) syncRevokedTokens(ctx context.Context) error {
+	rows, err := app.db.GetActiveRevokedTokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	entries := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		entries[row.Jti] = row.ExpiresAt
+	}
+
+	app.revokedTokens.Merge(entries)
+
+	return app.db.DeleteExpiredRevokedTokens(ctx)
+}
+
+func (app *application) runRevokedTokensSync(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := app.syncRevokedTokens(ctx)
+		cancel()
+
+		if err != nil {
+			app.logger.Error("failed to sync revoked tokens", "error", err)
+		}
+	}
+}
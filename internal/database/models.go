@@ -19,6 +19,7 @@ const (
 	OtpTypeEmailVerification OtpType = "email_verification"
 	OtpTypeTwoFactorAuth     OtpType = "two_factor_auth"
 	OtpTypeOtherType         OtpType = "other_type"
+	OtpTypeEmailChange       OtpType = "email_change"
 )
 
 func (e *OtpType) Scan(src interface{}) error {
@@ -56,23 +57,119 @@ func (ns NullOtpType) Value() (driver.Value, error) {
 	return string(ns.OtpType), nil
 }
 
+type AuditEvent struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Action    string    `json:"action"`
+	IpAddress string    `json:"ip_address"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Job struct {
+	ID          uuid.UUID  `json:"id"`
+	Kind        string     `json:"kind"`
+	Payload     []byte     `json:"-"`
+	UniqueKey   *string    `json:"unique_key"`
+	Attempts    int32      `json:"attempts"`
+	MaxAttempts int32      `json:"max_attempts"`
+	LastError   *string    `json:"last_error"`
+	RunAt       time.Time  `json:"run_at"`
+	FailedAt    *time.Time `json:"failed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
 type Otp struct {
 	ID          uuid.UUID  `json:"id"`
-	Code        string     `json:"code"`
+	Code        *string    `json:"-"`
 	Type        OtpType    `json:"type"`
 	UserID      *uuid.UUID `json:"user_id"`
 	ExpiresAt   time.Time  `json:"expires_at"`
 	CreatedAt   time.Time  `json:"created_at"`
 	Attempts    int32      `json:"attempts"`
 	MaxAttempts int32      `json:"max_attempts"`
+	CodeHash    []byte     `json:"-"`
+}
+
+type Permission struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+}
+
+type RateLimitBucket struct {
+	Key       string    `json:"key"`
+	Tokens    float64   `json:"tokens"`
+	Allowed   bool      `json:"allowed"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	CodeHash  []byte     `json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type RefreshToken struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	FamilyID   uuid.UUID  `json:"family_id"`
+	TokenHash  []byte     `json:"-"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	ReplacedBy *uuid.UUID `json:"replaced_by"`
+}
+
+type RevokedToken struct {
+	Jti       string    `json:"jti"`
+	UserID    uuid.UUID `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type Role struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type RolePermission struct {
+	RoleID       uuid.UUID `json:"role_id"`
+	PermissionID uuid.UUID `json:"permission_id"`
+}
+
+type TotpCredential struct {
+	UserID       uuid.UUID  `json:"user_id"`
+	Secret       string     `json:"-"`
+	LastUsedStep int64      `json:"last_used_step"`
+	ConfirmedAt  *time.Time `json:"confirmed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
 }
 
 type User struct {
-	ID             uuid.UUID  `json:"id"`
-	Name           string     `json:"name"`
-	Email          string     `json:"email"`
-	HashedPassword string     `json:"hashed_password"`
-	VerifiedAt     *time.Time `json:"verified_at"`
-	CreatedAt      time.Time  `json:"created_at"`
-	UpdatedAt      time.Time  `json:"updated_at"`
+	ID                  uuid.UUID  `json:"id"`
+	Name                string     `json:"name"`
+	Email               string     `json:"email"`
+	HashedPassword      string     `json:"-"`
+	VerifiedAt          *time.Time `json:"verified_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	TokensRevokedAt     *time.Time `json:"tokens_revoked_at"`
+	TwoFactorEnabled    bool       `json:"two_factor_enabled"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	PendingEmail        *string    `json:"pending_email"`
+	DeletedAt           *time.Time `json:"deleted_at"`
+	Locale              string     `json:"locale"`
+	FailedLoginAttempts int32      `json:"failed_login_attempts"`
+	LockedUntil         *time.Time `json:"locked_until"`
+}
+
+type UserRole struct {
+	UserID    uuid.UUID `json:"user_id"`
+	RoleID    uuid.UUID `json:"role_id"`
+	CreatedAt time.Time `json:"created_at"`
 }
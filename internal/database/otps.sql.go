@@ -12,28 +12,58 @@ import (
 	"github.com/google/uuid"
 )
 
-const createOTP = `-- name: CreateOTP :exec
-INSERT INTO otps (code, type, user_id, expires_at, created_at)
-VALUES ($1, $2, $5::UUID, $3, $4)
+const consumeOTP = `-- name: ConsumeOTP :one
+WITH latest AS (
+    SELECT id, code_hash,
+        expires_at <= CURRENT_TIMESTAMP AS expired,
+        attempts >= max_attempts AS exhausted
+    FROM otps
+    WHERE user_id = $1::UUID AND type = $2
+    ORDER BY created_at DESC
+    LIMIT 1
+    FOR UPDATE
+), consumed AS (
+    DELETE FROM otps
+    WHERE id IN (
+        SELECT id FROM latest
+        WHERE NOT expired AND NOT exhausted AND code_hash = $3
+    )
+    RETURNING id
+), attempted AS (
+    UPDATE otps
+    SET attempts = attempts + 1
+    WHERE id IN (
+        SELECT id FROM latest
+        WHERE NOT expired AND NOT exhausted AND code_hash IS DISTINCT FROM $3
+    )
+    RETURNING id
+)
+SELECT EXISTS (SELECT 1 FROM consumed) AS consumed, latest.expired, latest.exhausted
+FROM latest
 `
 
-type CreateOTPParams struct {
-	Code      string    `json:"code"`
-	Type      OtpType   `json:"type"`
-	ExpiresAt time.Time `json:"expires_at"`
-	CreatedAt time.Time `json:"created_at"`
-	UserID    uuid.UUID `json:"user_id"`
+type ConsumeOTPParams struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Type     OtpType   `json:"type"`
+	CodeHash []byte    `json:"-"`
 }
 
-func (q *Queries) CreateOTP(ctx context.Context, arg CreateOTPParams) error {
-	_, err := q.db.Exec(ctx, createOTP,
-		arg.Code,
-		arg.Type,
-		arg.ExpiresAt,
-		arg.CreatedAt,
-		arg.UserID,
-	)
-	return err
+type ConsumeOTPRow struct {
+	Consumed  bool `json:"consumed"`
+	Expired   bool `json:"expired"`
+	Exhausted bool `json:"exhausted"`
+}
+
+// Checks code_hash against the user's latest OTP of the given type. If the
+// OTP is still usable, it is deleted when the hash matches and has its
+// attempts incremented otherwise. The row is locked, so of two concurrent
+// calls with the right code only one consumes the OTP. The hashes are keyed
+// HMACs, so comparing them with = leaks nothing useful through timing.
+func (q *Queries) ConsumeOTP(ctx context.Context, arg ConsumeOTPParams) (ConsumeOTPRow, error) {
+	row := q.db.QueryRow(ctx, consumeOTP, arg.UserID, arg.Type, arg.CodeHash)
+	var i ConsumeOTPRow
+	err := row.Scan(&i.Consumed, &i.Expired, &i.Exhausted)
+	return i, err
 }
 
 const deleteOTP = `-- name: DeleteOTP :exec
@@ -58,7 +88,7 @@ func (q *Queries) ExpireOTP(ctx context.Context, id uuid.UUID) error {
 }
 
 const getLatestOTP = `-- name: GetLatestOTP :one
-SELECT id, code, type, user_id, expires_at, created_at, attempts, max_attempts FROM otps
+SELECT id, code, type, user_id, expires_at, created_at, attempts, max_attempts, code_hash FROM otps
 WHERE user_id = $2::UUID AND type = $1
 ORDER BY created_at DESC
 LIMIT 1
@@ -81,19 +111,79 @@ func (q *Queries) GetLatestOTP(ctx context.Context, arg GetLatestOTPParams) (Otp
 		&i.CreatedAt,
 		&i.Attempts,
 		&i.MaxAttempts,
+		&i.CodeHash,
 	)
 	return i, err
 }
 
-const incrementOTPAttempts = `-- name: IncrementOTPAttempts :exec
-UPDATE otps
-SET attempts = attempts + 1
-WHERE id = $1::UUID
+const getPlaintextOTPs = `-- name: GetPlaintextOTPs :many
+SELECT id, code FROM otps
+WHERE code IS NOT NULL
 `
 
-func (q *Queries) IncrementOTPAttempts(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.Exec(ctx, incrementOTPAttempts, id)
-	return err
+type GetPlaintextOTPsRow struct {
+	ID   uuid.UUID `json:"id"`
+	Code *string   `json:"-"`
+}
+
+func (q *Queries) GetPlaintextOTPs(ctx context.Context) ([]GetPlaintextOTPsRow, error) {
+	rows, err := q.db.Query(ctx, getPlaintextOTPs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPlaintextOTPsRow
+	for rows.Next() {
+		var i GetPlaintextOTPsRow
+		if err := rows.Scan(&i.ID, &i.Code); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserOTPs = `-- name: GetUserOTPs :many
+SELECT id, type, expires_at, created_at, attempts FROM otps
+WHERE user_id = $1::UUID
+ORDER BY created_at
+`
+
+type GetUserOTPsRow struct {
+	ID        uuid.UUID `json:"id"`
+	Type      OtpType   `json:"type"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	Attempts  int32     `json:"attempts"`
+}
+
+func (q *Queries) GetUserOTPs(ctx context.Context, userID uuid.UUID) ([]GetUserOTPsRow, error) {
+	rows, err := q.db.Query(ctx, getUserOTPs, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserOTPsRow
+	for rows.Next() {
+		var i GetUserOTPsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Type,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+			&i.Attempts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const invalidateExistingOTP = `-- name: InvalidateExistingOTP :exec
@@ -110,3 +200,51 @@ func (q *Queries) InvalidateExistingOTP(ctx context.Context, arg InvalidateExist
 	_, err := q.db.Exec(ctx, invalidateExistingOTP, arg.Type, arg.UserID)
 	return err
 }
+
+const replaceOTP = `-- name: ReplaceOTP :exec
+WITH deleted AS (
+    DELETE FROM otps
+    WHERE user_id = $1::UUID AND type = $2
+)
+INSERT INTO otps (code_hash, type, user_id, max_attempts, expires_at, created_at)
+VALUES ($3, $2, $1::UUID, $4, $5, $6)
+`
+
+type ReplaceOTPParams struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Type        OtpType   `json:"type"`
+	CodeHash    []byte    `json:"-"`
+	MaxAttempts int32     `json:"max_attempts"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Deletes the user's OTPs of the given type and inserts the new one in a
+// single statement.
+func (q *Queries) ReplaceOTP(ctx context.Context, arg ReplaceOTPParams) error {
+	_, err := q.db.Exec(ctx, replaceOTP,
+		arg.UserID,
+		arg.Type,
+		arg.CodeHash,
+		arg.MaxAttempts,
+		arg.ExpiresAt,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const setOTPCodeHash = `-- name: SetOTPCodeHash :exec
+UPDATE otps
+SET code_hash = $1, code = NULL
+WHERE id = $2::UUID
+`
+
+type SetOTPCodeHashParams struct {
+	CodeHash []byte    `json:"-"`
+	ID       uuid.UUID `json:"id"`
+}
+
+func (q *Queries) SetOTPCodeHash(ctx context.Context, arg SetOTPCodeHashParams) error {
+	_, err := q.db.Exec(ctx, setOTPCodeHash, arg.CodeHash, arg.ID)
+	return err
+}
@@ -12,14 +12,47 @@ import (
 	"github.com/google/uuid"
 )
 
+const confirmUserEmailChange = `-- name: ConfirmUserEmailChange :one
+UPDATE users
+SET email = pending_email,
+    pending_email = NULL,
+    verified_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1::UUID AND pending_email IS NOT NULL
+RETURNING id, name, email, hashed_password, verified_at, created_at, updated_at, tokens_revoked_at, two_factor_enabled, disabled_at, pending_email, deleted_at, locale, failed_login_attempts, locked_until
+`
+
+func (q *Queries) ConfirmUserEmailChange(ctx context.Context, userID uuid.UUID) (User, error) {
+	row := q.db.QueryRow(ctx, confirmUserEmailChange, userID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.HashedPassword,
+		&i.VerifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TokensRevokedAt,
+		&i.TwoFactorEnabled,
+		&i.DisabledAt,
+		&i.PendingEmail,
+		&i.DeletedAt,
+		&i.Locale,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (email, name, hashed_password) VALUES ($1, $2, $3) RETURNING id, name, email, hashed_password, verified_at, created_at, updated_at
+INSERT INTO users (email, name, hashed_password) VALUES ($1, $2, $3) RETURNING id, name, email, hashed_password, verified_at, created_at, updated_at, tokens_revoked_at, two_factor_enabled, disabled_at, pending_email, deleted_at, locale, failed_login_attempts, locked_until
 `
 
 type CreateUserParams struct {
 	Email          string `json:"email"`
 	Name           string `json:"name"`
-	HashedPassword string `json:"hashed_password"`
+	HashedPassword string `json:"-"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -33,12 +66,30 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.VerifiedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TokensRevokedAt,
+		&i.TwoFactorEnabled,
+		&i.DisabledAt,
+		&i.PendingEmail,
+		&i.DeletedAt,
+		&i.Locale,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
 	)
 	return i, err
 }
 
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUser, id)
+	return err
+}
+
 const getUser = `-- name: GetUser :one
-SELECT id, name, email, hashed_password, verified_at, created_at, updated_at FROM users WHERE id = $1
+SELECT id, name, email, hashed_password, verified_at, created_at, updated_at, tokens_revoked_at, two_factor_enabled, disabled_at, pending_email, deleted_at, locale, failed_login_attempts, locked_until FROM users WHERE id = $1
 `
 
 func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
@@ -52,12 +103,20 @@ func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.VerifiedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TokensRevokedAt,
+		&i.TwoFactorEnabled,
+		&i.DisabledAt,
+		&i.PendingEmail,
+		&i.DeletedAt,
+		&i.Locale,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, name, email, hashed_password, verified_at, created_at, updated_at FROM users WHERE email = $1
+SELECT id, name, email, hashed_password, verified_at, created_at, updated_at, tokens_revoked_at, two_factor_enabled, disabled_at, pending_email, deleted_at, locale, failed_login_attempts, locked_until FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -71,23 +130,86 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.VerifiedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TokensRevokedAt,
+		&i.TwoFactorEnabled,
+		&i.DisabledAt,
+		&i.PendingEmail,
+		&i.DeletedAt,
+		&i.Locale,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
 	)
 	return i, err
 }
 
 const getUsers = `-- name: GetUsers :many
-SELECT id, name, email, hashed_password, verified_at, created_at, updated_at FROM users
+SELECT id, name, email, hashed_password, verified_at, created_at, updated_at, tokens_revoked_at, two_factor_enabled, disabled_at, pending_email, deleted_at, locale, failed_login_attempts, locked_until, count(*) OVER() AS total_count FROM users
+WHERE ($1::TEXT IS NULL OR email ILIKE '%' || $1 || '%')
+  AND ($2::BOOLEAN IS NULL OR (verified_at IS NOT NULL) = $2)
+  AND ($3::BOOLEAN IS NULL OR (disabled_at IS NOT NULL) = $3)
+  AND ($4::TIMESTAMPTZ IS NULL
+    OR ($5::TEXT = 'created_at' AND (created_at, id) > ($4, $6::UUID))
+    OR ($5 = '-created_at' AND (created_at, id) < ($4, $6)))
+ORDER BY
+  CASE WHEN $5 = 'created_at' THEN created_at END ASC,
+  CASE WHEN $5 = '-created_at' THEN created_at END DESC,
+  CASE WHEN $5 = 'email' THEN email END ASC,
+  CASE WHEN $5 = '-email' THEN email END DESC,
+  CASE WHEN $5 = 'name' THEN name END ASC,
+  CASE WHEN $5 = '-name' THEN name END DESC,
+  CASE WHEN $5 LIKE '-%' THEN id END DESC,
+  id ASC
+LIMIT $7::INT OFFSET $8::INT
 `
 
-func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
-	rows, err := q.db.Query(ctx, getUsers)
+type GetUsersParams struct {
+	Email           *string    `json:"email"`
+	Verified        *bool      `json:"verified"`
+	Disabled        *bool      `json:"disabled"`
+	CursorCreatedAt *time.Time `json:"cursor_created_at"`
+	Sort            string     `json:"sort"`
+	CursorID        *uuid.UUID `json:"cursor_id"`
+	PageLimit       int32      `json:"page_limit"`
+	PageOffset      int32      `json:"page_offset"`
+}
+
+type GetUsersRow struct {
+	ID                  uuid.UUID  `json:"id"`
+	Name                string     `json:"name"`
+	Email               string     `json:"email"`
+	HashedPassword      string     `json:"-"`
+	VerifiedAt          *time.Time `json:"verified_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	TokensRevokedAt     *time.Time `json:"tokens_revoked_at"`
+	TwoFactorEnabled    bool       `json:"two_factor_enabled"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	PendingEmail        *string    `json:"pending_email"`
+	DeletedAt           *time.Time `json:"deleted_at"`
+	Locale              string     `json:"locale"`
+	FailedLoginAttempts int32      `json:"failed_login_attempts"`
+	LockedUntil         *time.Time `json:"locked_until"`
+	TotalCount          int64      `json:"total_count"`
+}
+
+func (q *Queries) GetUsers(ctx context.Context, arg GetUsersParams) ([]GetUsersRow, error) {
+	rows, err := q.db.Query(ctx, getUsers,
+		arg.Email,
+		arg.Verified,
+		arg.Disabled,
+		arg.CursorCreatedAt,
+		arg.Sort,
+		arg.CursorID,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []User
+	var items []GetUsersRow
 	for rows.Next() {
-		var i User
+		var i GetUsersRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.Name,
@@ -96,6 +218,15 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 			&i.VerifiedAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.TokensRevokedAt,
+			&i.TwoFactorEnabled,
+			&i.DisabledAt,
+			&i.PendingEmail,
+			&i.DeletedAt,
+			&i.Locale,
+			&i.FailedLoginAttempts,
+			&i.LockedUntil,
+			&i.TotalCount,
 		); err != nil {
 			return nil, err
 		}
@@ -107,9 +238,250 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 	return items, nil
 }
 
+const lockUser = `-- name: LockUser :exec
+UPDATE users
+SET locked_until = $1::TIMESTAMPTZ
+WHERE id = $2::UUID
+`
+
+type LockUserParams struct {
+	LockedUntil time.Time `json:"locked_until"`
+	UserID      uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) LockUser(ctx context.Context, arg LockUserParams) error {
+	_, err := q.db.Exec(ctx, lockUser, arg.LockedUntil, arg.UserID)
+	return err
+}
+
+const purgeDeletedUsers = `-- name: PurgeDeletedUsers :execrows
+DELETE FROM users
+WHERE deleted_at <= $1::TIMESTAMPTZ
+`
+
+func (q *Queries) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeDeletedUsers, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const recordFailedLogin = `-- name: RecordFailedLogin :one
+UPDATE users
+SET failed_login_attempts = failed_login_attempts + 1
+WHERE id = $1
+RETURNING failed_login_attempts
+`
+
+func (q *Queries) RecordFailedLogin(ctx context.Context, id uuid.UUID) (int32, error) {
+	row := q.db.QueryRow(ctx, recordFailedLogin, id)
+	var failed_login_attempts int32
+	err := row.Scan(&failed_login_attempts)
+	return failed_login_attempts, err
+}
+
+const resetFailedLogins = `-- name: ResetFailedLogins :exec
+UPDATE users
+SET failed_login_attempts = 0, locked_until = NULL
+WHERE id = $1
+`
+
+func (q *Queries) ResetFailedLogins(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, resetFailedLogins, id)
+	return err
+}
+
+const restoreDeletedUser = `-- name: RestoreDeletedUser :execrows
+UPDATE users
+SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) RestoreDeletedUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, restoreDeletedUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const revokeUserTokens = `-- name: RevokeUserTokens :exec
+UPDATE users
+SET tokens_revoked_at = $1::TIMESTAMPTZ
+WHERE id = $2::UUID
+`
+
+type RevokeUserTokensParams struct {
+	TokensRevokedAt time.Time `json:"tokens_revoked_at"`
+	UserID          uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) RevokeUserTokens(ctx context.Context, arg RevokeUserTokensParams) error {
+	_, err := q.db.Exec(ctx, revokeUserTokens, arg.TokensRevokedAt, arg.UserID)
+	return err
+}
+
+const setUserDeleted = `-- name: SetUserDeleted :exec
+UPDATE users
+SET deleted_at = $1::TIMESTAMPTZ, updated_at = CURRENT_TIMESTAMP
+WHERE id = $2::UUID
+`
+
+type SetUserDeletedParams struct {
+	DeletedAt time.Time `json:"deleted_at"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) SetUserDeleted(ctx context.Context, arg SetUserDeletedParams) error {
+	_, err := q.db.Exec(ctx, setUserDeleted, arg.DeletedAt, arg.UserID)
+	return err
+}
+
+const setUserDisabled = `-- name: SetUserDisabled :exec
+UPDATE users
+SET disabled_at = $1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $2::UUID
+`
+
+type SetUserDisabledParams struct {
+	DisabledAt *time.Time `json:"disabled_at"`
+	UserID     uuid.UUID  `json:"user_id"`
+}
+
+func (q *Queries) SetUserDisabled(ctx context.Context, arg SetUserDisabledParams) error {
+	_, err := q.db.Exec(ctx, setUserDisabled, arg.DisabledAt, arg.UserID)
+	return err
+}
+
+const setUserPendingEmail = `-- name: SetUserPendingEmail :exec
+UPDATE users
+SET pending_email = $1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $2::UUID
+`
+
+type SetUserPendingEmailParams struct {
+	PendingEmail *string   `json:"pending_email"`
+	UserID       uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) SetUserPendingEmail(ctx context.Context, arg SetUserPendingEmailParams) error {
+	_, err := q.db.Exec(ctx, setUserPendingEmail, arg.PendingEmail, arg.UserID)
+	return err
+}
+
+const setUserTwoFactorEnabled = `-- name: SetUserTwoFactorEnabled :exec
+UPDATE users
+SET two_factor_enabled = $1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $2::UUID
+`
+
+type SetUserTwoFactorEnabledParams struct {
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
+	UserID           uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) SetUserTwoFactorEnabled(ctx context.Context, arg SetUserTwoFactorEnabledParams) error {
+	_, err := q.db.Exec(ctx, setUserTwoFactorEnabled, arg.TwoFactorEnabled, arg.UserID)
+	return err
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE users
+SET name = $1,
+    email = $2,
+    verified_at = CASE WHEN email = $2 THEN verified_at ELSE NULL END,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $3::UUID
+RETURNING id, name, email, hashed_password, verified_at, created_at, updated_at, tokens_revoked_at, two_factor_enabled, disabled_at, pending_email, deleted_at, locale, failed_login_attempts, locked_until
+`
+
+type UpdateUserParams struct {
+	Name   string    `json:"name"`
+	Email  string    `json:"email"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUser, arg.Name, arg.Email, arg.UserID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.HashedPassword,
+		&i.VerifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TokensRevokedAt,
+		&i.TwoFactorEnabled,
+		&i.DisabledAt,
+		&i.PendingEmail,
+		&i.DeletedAt,
+		&i.Locale,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $2::UUID
+`
+
+type UpdateUserPasswordParams struct {
+	HashedPassword string    `json:"-"`
+	UserID         uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.Exec(ctx, updateUserPassword, arg.HashedPassword, arg.UserID)
+	return err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET name = COALESCE($1, name),
+    locale = COALESCE($2, locale),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $3::UUID
+RETURNING id, name, email, hashed_password, verified_at, created_at, updated_at, tokens_revoked_at, two_factor_enabled, disabled_at, pending_email, deleted_at, locale, failed_login_attempts, locked_until
+`
+
+type UpdateUserProfileParams struct {
+	Name   *string   `json:"name"`
+	Locale *string   `json:"locale"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserProfile, arg.Name, arg.Locale, arg.UserID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.HashedPassword,
+		&i.VerifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TokensRevokedAt,
+		&i.TwoFactorEnabled,
+		&i.DisabledAt,
+		&i.PendingEmail,
+		&i.DeletedAt,
+		&i.Locale,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
 const verifyUser = `-- name: VerifyUser :exec
 UPDATE users
-SET verified_at = $1::TIMESTAMPTZ
+SET verified_at = $1::TIMESTAMPTZ, updated_at = CURRENT_TIMESTAMP
 WHERE id = $2::UUID
 `
 
@@ -0,0 +1,159 @@
+// Package dto defines the shapes in which database models are returned to
+// API clients. Handlers map sqlc models to these types before encoding them,
+// so columns such as password hashes and OTP codes are never serialized.
+package dto
+
+import (
+	"time"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+
+	"github.com/google/uuid"
+)
+
+type User struct {
+	ID               uuid.UUID  `json:"id"`
+	Name             string     `json:"name"`
+	Email            string     `json:"email"`
+	PendingEmail     *string    `json:"pending_email"`
+	VerifiedAt       *time.Time `json:"verified_at"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	DisabledAt       *time.Time `json:"disabled_at"`
+	DeletedAt        *time.Time `json:"deleted_at"`
+	Locale           string     `json:"locale"`
+	LockedUntil      *time.Time `json:"locked_until"`
+}
+
+func NewUser(u database.User) User {
+	return User{
+		ID:               u.ID,
+		Name:             u.Name,
+		Email:            u.Email,
+		PendingEmail:     u.PendingEmail,
+		VerifiedAt:       u.VerifiedAt,
+		TwoFactorEnabled: u.TwoFactorEnabled,
+		CreatedAt:        u.CreatedAt,
+		UpdatedAt:        u.UpdatedAt,
+		DisabledAt:       u.DisabledAt,
+		DeletedAt:        u.DeletedAt,
+		Locale:           u.Locale,
+		LockedUntil:      u.LockedUntil,
+	}
+}
+
+func NewUsersFromRows(rows []database.GetUsersRow) []User {
+	users := make([]User, len(rows))
+	for i, row := range rows {
+		users[i] = User{
+			ID:               row.ID,
+			Name:             row.Name,
+			Email:            row.Email,
+			PendingEmail:     row.PendingEmail,
+			VerifiedAt:       row.VerifiedAt,
+			TwoFactorEnabled: row.TwoFactorEnabled,
+			CreatedAt:        row.CreatedAt,
+			UpdatedAt:        row.UpdatedAt,
+			DisabledAt:       row.DisabledAt,
+			DeletedAt:        row.DeletedAt,
+			Locale:           row.Locale,
+			LockedUntil:      row.LockedUntil,
+		}
+	}
+
+	return users
+}
+
+// OTP describes a one-time password without its code.
+type OTP struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	Attempts  int32     `json:"attempts"`
+}
+
+func NewOTPs(rows []database.GetUserOTPsRow) []OTP {
+	otps := make([]OTP, len(rows))
+	for i, row := range rows {
+		otps[i] = OTP{
+			ID:        row.ID,
+			Type:      string(row.Type),
+			ExpiresAt: row.ExpiresAt,
+			CreatedAt: row.CreatedAt,
+			Attempts:  row.Attempts,
+		}
+	}
+
+	return otps
+}
+
+type AuditEvent struct {
+	ID        uuid.UUID `json:"id"`
+	Action    string    `json:"action"`
+	IPAddress string    `json:"ip_address"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewAuditEvents(events []database.AuditEvent) []AuditEvent {
+	dtos := make([]AuditEvent, len(events))
+	for i, e := range events {
+		dtos[i] = AuditEvent{
+			ID:        e.ID,
+			Action:    e.Action,
+			IPAddress: e.IpAddress,
+			CreatedAt: e.CreatedAt,
+		}
+	}
+
+	return dtos
+}
+
+// Job describes a background job without its payload, which may hold
+// personal data such as a rendered email.
+type Job struct {
+	ID          uuid.UUID  `json:"id"`
+	Kind        string     `json:"kind"`
+	UniqueKey   *string    `json:"unique_key"`
+	Attempts    int32      `json:"attempts"`
+	MaxAttempts int32      `json:"max_attempts"`
+	LastError   *string    `json:"last_error"`
+	FailedAt    *time.Time `json:"failed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func NewJobsFromRows(rows []database.GetFailedJobsRow) []Job {
+	jobs := make([]Job, len(rows))
+	for i, row := range rows {
+		jobs[i] = Job{
+			ID:          row.ID,
+			Kind:        row.Kind,
+			UniqueKey:   row.UniqueKey,
+			Attempts:    row.Attempts,
+			MaxAttempts: row.MaxAttempts,
+			LastError:   row.LastError,
+			FailedAt:    row.FailedAt,
+			CreatedAt:   row.CreatedAt,
+		}
+	}
+
+	return jobs
+}
+
+// JobStats counts the jobs of one kind in the jobs table.
+type JobStats struct {
+	Kind     string `json:"kind"`
+	Pending  int64  `json:"pending"`
+	Retrying int64  `json:"retrying"`
+	Failed   int64  `json:"failed"`
+}
+
+func NewJobStats(rows []database.GetJobStatsRow) []JobStats {
+	stats := make([]JobStats, len(rows))
+	for i, row := range rows {
+		stats[i] = JobStats(row)
+	}
+
+	return stats
+}
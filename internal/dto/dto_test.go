@@ -0,0 +1,126 @@
+package dto
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// dtoTypes holds a value of every type declared in this package.
+var dtoTypes = []any{
+	AuditEvent{},
+	Job{},
+	JobStats{},
+	OTP{},
+	User{},
+}
+
+// sensitiveFields are JSON names that must never be sent to clients.
+var sensitiveFields = []string{
+	"code",
+	"code_hash",
+	"hashed_password",
+	"payload",
+	"secret",
+	"token_hash",
+}
+
+func TestTypesAreListed(t *testing.T) {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), ".", func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listed := map[string]bool{}
+	for _, v := range dtoTypes {
+		listed[reflect.TypeOf(v).Name()] = true
+	}
+
+	for _, file := range pkgs["dto"].Files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range gen.Specs {
+				name := spec.(*ast.TypeSpec).Name.Name
+				if !listed[name] {
+					t.Errorf("type %s is missing from dtoTypes", name)
+				}
+			}
+		}
+	}
+}
+
+func TestNoDatabaseModels(t *testing.T) {
+	for _, v := range dtoTypes {
+		typ := reflect.TypeOf(v)
+
+		t.Run(typ.Name(), func(t *testing.T) {
+			path := findDatabaseModel(typ, map[reflect.Type]bool{})
+			if path != "" {
+				t.Errorf("%s holds the database model %s", typ.Name(), path)
+			}
+		})
+	}
+}
+
+func TestNoSensitiveFields(t *testing.T) {
+	for _, v := range dtoTypes {
+		typ := reflect.TypeOf(v)
+
+		t.Run(typ.Name(), func(t *testing.T) {
+			for i := 0; i < typ.NumField(); i++ {
+				name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+
+				for _, sensitive := range sensitiveFields {
+					if name == sensitive {
+						t.Errorf("field %s is encoded as %q", typ.Field(i).Name, name)
+					}
+				}
+			}
+		})
+	}
+}
+
+// findDatabaseModel returns the name of a struct type from internal/database
+// that typ is or contains, or an empty string.
+func findDatabaseModel(typ reflect.Type, seen map[reflect.Type]bool) string {
+	if seen[typ] {
+		return ""
+	}
+	seen[typ] = true
+
+	switch typ.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return findDatabaseModel(typ.Elem(), seen)
+	case reflect.Map:
+		if path := findDatabaseModel(typ.Key(), seen); path != "" {
+			return path
+		}
+		return findDatabaseModel(typ.Elem(), seen)
+	case reflect.Struct:
+		if strings.HasSuffix(typ.PkgPath(), "/internal/database") {
+			return typ.String()
+		}
+
+		for i := 0; i < typ.NumField(); i++ {
+			if !typ.Field(i).IsExported() {
+				continue
+			}
+
+			if path := findDatabaseModel(typ.Field(i).Type, seen); path != "" {
+				return path
+			}
+		}
+	}
+
+	return ""
+}
@@ -3,6 +3,8 @@ package env
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 func GetString(key, defaultValue string) string {
@@ -41,3 +43,34 @@ func GetBool(key string, defaultValue bool) bool {
 
 	return boolValue
 }
+
+func GetDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	durationValue, err := time.ParseDuration(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return durationValue
+}
+
+func GetStringSlice(key string, defaultValue []string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,125 @@
+// Package i18n translates user-facing messages. Messages are looked up by
+// their stable code in the JSON catalogs in assets/locales, one file per
+// language. English is the source language: it has no catalog, and any
+// message missing from a catalog falls back to the English text.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/jcarloasilo/golang-rest-template/assets"
+
+	"golang.org/x/text/language"
+)
+
+var (
+	catalogs  = map[language.Tag]map[string]string{}
+	supported = []language.Tag{language.English}
+	matcher   language.Matcher
+)
+
+func init() {
+	files, err := fs.Glob(assets.EmbeddedFiles, "locales/*.json")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, file := range files {
+		tag := language.MustParse(strings.TrimSuffix(path.Base(file), ".json"))
+
+		data, err := fs.ReadFile(assets.EmbeddedFiles, file)
+		if err != nil {
+			panic(err)
+		}
+
+		messages := map[string]string{}
+		err = json.Unmarshal(data, &messages)
+		if err != nil {
+			panic(fmt.Errorf("i18n: %s: %w", file, err))
+		}
+
+		catalogs[tag] = messages
+		supported = append(supported, tag)
+	}
+
+	matcher = language.NewMatcher(supported)
+}
+
+// Supported reports whether there is a catalog for the locale, or the locale
+// is English.
+func Supported(locale string) bool {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return false
+	}
+
+	_, _, confidence := matcher.Match(tag)
+	return confidence >= language.High
+}
+
+// Localizer translates messages into a single language.
+type Localizer struct {
+	tag      language.Tag
+	messages map[string]string
+}
+
+// New returns a Localizer for the first of the given preferences that can be
+// satisfied, falling back to English. Each preference is either a single
+// locale such as "es" or the value of an Accept-Language header; empty
+// preferences are skipped.
+func New(preferences ...string) Localizer {
+	for _, preference := range preferences {
+		if preference == "" {
+			continue
+		}
+
+		tags, _, err := language.ParseAcceptLanguage(preference)
+		if err != nil || len(tags) == 0 {
+			continue
+		}
+
+		_, index, confidence := matcher.Match(tags...)
+		if confidence == language.No {
+			continue
+		}
+
+		tag := supported[index]
+		return Localizer{tag: tag, messages: catalogs[tag]}
+	}
+
+	return Localizer{tag: language.English}
+}
+
+// Locale returns the BCP 47 tag of the language, such as "en" or "es".
+func (l Localizer) Locale() string {
+	return l.tag.String()
+}
+
+// Translate returns the message for code, or fallback if there is none.
+// Placeholders such as {min} are replaced with the matching value from
+// params.
+func (l Localizer) Translate(code, fallback string, params map[string]any) string {
+	message, ok := l.messages[code]
+	if !ok {
+		return fallback
+	}
+
+	for key, value := range params {
+		message = strings.ReplaceAll(message, "{"+key+"}", formatParam(value))
+	}
+
+	return message
+}
+
+func formatParam(value any) string {
+	switch v := value.(type) {
+	case []string:
+		return strings.Join(v, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,346 @@
+// Package jobs runs background jobs that are stored in the jobs table, so
+// they survive restarts and can be shared between instances. Each kind of
+// job has a typed handler, registered with Register before the queue is
+// started. A job that fails is retried with exponential backoff until it
+// runs out of attempts, and is then kept as failed.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+)
+
+var ErrUnknownKind = errors.New("jobs: no handler registered for kind")
+
+// Options configures a Queue. Zero values are replaced with defaults.
+type Options struct {
+	// Workers is the number of jobs run at the same time. Defaults to 1.
+	Workers int
+	// PollInterval is how often workers look for due jobs when they haven't
+	// been woken by Enqueue. Defaults to 1 second.
+	PollInterval time.Duration
+	// Timeout is how long a job may run before its context is cancelled.
+	// A job that is still held after that is claimed again. Defaults to 5
+	// minutes.
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts of jobs enqueued without their
+	// own. Defaults to 10.
+	MaxAttempts int
+}
+
+// EnqueueOptions configures a single job.
+type EnqueueOptions struct {
+	// RunAt delays the job until the given time. The zero value runs it as
+	// soon as possible.
+	RunAt time.Time
+	// UniqueKey, if set, skips the job when another job with the same key is
+	// waiting to run or running.
+	UniqueKey string
+	// MaxAttempts overrides the queue's number of attempts.
+	MaxAttempts int
+}
+
+type handlerFunc func(ctx context.Context, payload []byte) error
+
+// Counts is what this instance's workers have done with jobs of one kind
+// since the queue was created. Retried counts failed attempts that will be
+// tried again, and Failed the jobs that ran out of attempts.
+type Counts struct {
+	Succeeded int64 `json:"succeeded"`
+	Retried   int64 `json:"retried"`
+	Failed    int64 `json:"failed"`
+}
+
+type counters struct {
+	succeeded atomic.Int64
+	retried   atomic.Int64
+	failed    atomic.Int64
+}
+
+// Queue enqueues jobs and runs them with its workers.
+type Queue struct {
+	db       *database.Queries
+	logger   *slog.Logger
+	opts     Options
+	handlers map[string]handlerFunc
+	counters map[string]*counters
+
+	wake   chan struct{}
+	stop   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewQueue(db *database.Queries, logger *slog.Logger, opts Options) *Queue {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Minute
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 10
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Queue{
+		db:       db,
+		logger:   logger,
+		opts:     opts,
+		handlers: make(map[string]handlerFunc),
+		counters: make(map[string]*counters),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Register sets the handler for jobs of the given kind. Their payload is
+// decoded from JSON into a T before the handler is called. It must be called
+// before Start.
+func Register[T any](q *Queue, kind string, handler func(ctx context.Context, args T) error) {
+	q.handlers[kind] = func(ctx context.Context, payload []byte) error {
+		var args T
+
+		err := json.Unmarshal(payload, &args)
+		if err != nil {
+			return fmt.Errorf("jobs: decoding %s payload: %w", kind, err)
+		}
+
+		return handler(ctx, args)
+	}
+	q.counters[kind] = &counters{}
+}
+
+// Counts returns the counts of each registered kind of job.
+func (q *Queue) Counts() map[string]Counts {
+	counts := make(map[string]Counts, len(q.counters))
+	for kind, c := range q.counters {
+		counts[kind] = Counts{
+			Succeeded: c.succeeded.Load(),
+			Retried:   c.retried.Load(),
+			Failed:    c.failed.Load(),
+		}
+	}
+
+	return counts
+}
+
+// Enqueue adds a job of the given kind with args as its payload. It returns
+// false if the job was skipped because of its unique key.
+func (q *Queue) Enqueue(ctx context.Context, kind string, args any, opts EnqueueOptions) (bool, error) {
+	return q.EnqueueTx(ctx, q.db, kind, args, opts)
+}
+
+// EnqueueTx is Enqueue using db, which can be bound to a transaction so that
+// the job only exists if the transaction commits.
+func (q *Queue) EnqueueTx(ctx context.Context, db *database.Queries, kind string, args any, opts EnqueueOptions) (bool, error) {
+	if _, ok := q.handlers[kind]; !ok {
+		return false, fmt.Errorf("%w %q", ErrUnknownKind, kind)
+	}
+
+	payload, err := json.Marshal(args)
+	if err != nil {
+		return false, err
+	}
+
+	params := database.InsertJobParams{
+		Kind:        kind,
+		Payload:     payload,
+		MaxAttempts: int32(q.opts.MaxAttempts),
+		RunAt:       opts.RunAt,
+	}
+	if opts.UniqueKey != "" {
+		params.UniqueKey = &opts.UniqueKey
+	}
+	if opts.MaxAttempts > 0 {
+		params.MaxAttempts = int32(opts.MaxAttempts)
+	}
+	if params.RunAt.IsZero() {
+		params.RunAt = time.Now()
+	}
+
+	inserted, err := db.InsertJob(ctx, params)
+	if err != nil {
+		return false, err
+	}
+
+	if inserted > 0 && !params.RunAt.After(time.Now()) {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return inserted > 0, nil
+}
+
+// Start starts the workers.
+func (q *Queue) Start() {
+	for range q.opts.Workers {
+		q.wg.Add(1)
+		go q.work()
+	}
+}
+
+// Shutdown stops the workers from claiming new jobs and waits for the jobs
+// that are running to finish. If ctx is done first, the running jobs have
+// their contexts cancelled, and are claimed again once their timeout has
+// passed.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	close(q.stop)
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		q.runDue()
+
+		select {
+		case <-q.stop:
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDue runs due jobs until there are none left or the queue is stopping.
+func (q *Queue) runDue() {
+	kinds := make([]string, 0, len(q.handlers))
+	for kind := range q.handlers {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		job, err := q.db.ClaimJob(q.ctx, database.ClaimJobParams{
+			LeaseUntil: time.Now().Add(q.opts.Timeout),
+			Kinds:      kinds,
+		})
+		if err != nil {
+			if !errors.Is(err, pgx.ErrNoRows) && !errors.Is(err, context.Canceled) {
+				q.logger.Error("failed to claim job", "error", err)
+			}
+			return
+		}
+
+		q.run(job)
+	}
+}
+
+func (q *Queue) run(job database.Job) {
+	ctx, cancel := context.WithTimeout(q.ctx, q.opts.Timeout)
+	err := q.call(ctx, job)
+	cancel()
+
+	// Record the outcome even if the queue is being shut down.
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	counters := q.counters[job.Kind]
+
+	if err == nil {
+		counters.succeeded.Add(1)
+
+		err = q.db.DeleteJob(ctx, job.ID)
+		if err != nil {
+			q.logger.Error("failed to delete finished job", "id", job.ID, "kind", job.Kind, "error", err)
+		}
+		return
+	}
+
+	lastError := err.Error()
+
+	if job.Attempts >= job.MaxAttempts {
+		counters.failed.Add(1)
+		q.logger.Error("job failed", "id", job.ID, "kind", job.Kind, "attempts", job.Attempts, "error", err)
+
+		err = q.db.FailJob(ctx, database.FailJobParams{
+			LastError: &lastError,
+			ID:        job.ID,
+		})
+	} else {
+		counters.retried.Add(1)
+		retryIn := backoff(job.Attempts)
+		q.logger.Warn("job will be retried", "id", job.ID, "kind", job.Kind, "attempts", job.Attempts, "retry_in", retryIn, "error", err)
+
+		err = q.db.RetryJob(ctx, database.RetryJobParams{
+			LastError: &lastError,
+			RunAt:     time.Now().Add(retryIn),
+			ID:        job.ID,
+		})
+	}
+	if err != nil {
+		q.logger.Error("failed to record job failure", "id", job.ID, "kind", job.Kind, "error", err)
+	}
+}
+
+// call runs the job's handler, turning a panic into an error.
+func (q *Queue) call(ctx context.Context, job database.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("jobs: panic in %s handler: %v", job.Kind, r)
+		}
+	}()
+
+	return q.handlers[job.Kind](ctx, job.Payload)
+}
+
+// backoff returns how long to wait before retrying a job that has failed
+// attempts times, doubling each time up to maxBackoff.
+func backoff(attempts int32) time.Duration {
+	d := baseBackoff
+	for i := int32(1); i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+
+	return min(d, maxBackoff)
+}
@@ -0,0 +1,206 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+)
+
+func newTestQueue() *Queue {
+	return NewQueue(nil, slog.New(slog.NewTextHandler(io.Discard, nil)), Options{})
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int32
+		want     time.Duration
+	}{
+		{attempts: 0, want: 10 * time.Second},
+		{attempts: 1, want: 10 * time.Second},
+		{attempts: 2, want: 20 * time.Second},
+		{attempts: 3, want: 40 * time.Second},
+		{attempts: 9, want: 2560 * time.Second},
+		{attempts: 10, want: time.Hour},
+		{attempts: 1000, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s; want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestNewQueueDefaults(t *testing.T) {
+	q := newTestQueue()
+
+	want := Options{Workers: 1, PollInterval: time.Second, Timeout: 5 * time.Minute, MaxAttempts: 10}
+	if q.opts != want {
+		t.Errorf("got options %+v; want %+v", q.opts, want)
+	}
+}
+
+type testArgs struct {
+	Name string `json:"name"`
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		wantName string
+		wantErr  bool
+	}{
+		{name: "Valid payload", payload: `{"name":"alice"}`, wantName: "alice"},
+		{name: "Unknown fields", payload: `{"name":"alice","extra":1}`, wantName: "alice"},
+		{name: "Invalid JSON", payload: `{"name":`, wantErr: true},
+		{name: "Wrong type", payload: `{"name":1}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newTestQueue()
+
+			var got testArgs
+			called := false
+
+			Register(q, "test", func(ctx context.Context, args testArgs) error {
+				called = true
+				got = args
+				return nil
+			})
+
+			err := q.call(context.Background(), database.Job{Kind: "test", Payload: []byte(tt.payload)})
+
+			switch {
+			case tt.wantErr && err == nil:
+				t.Error("got nil error; want a decoding error")
+			case tt.wantErr && called:
+				t.Error("handler was called with a payload that could not be decoded")
+			case !tt.wantErr && err != nil:
+				t.Errorf("got error %v; want nil", err)
+			case !tt.wantErr && got.Name != tt.wantName:
+				t.Errorf("got name %q; want %q", got.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestCallReturnsHandlerError(t *testing.T) {
+	q := newTestQueue()
+	errTest := errors.New("test error")
+
+	Register(q, "test", func(ctx context.Context, args testArgs) error {
+		return errTest
+	})
+
+	err := q.call(context.Background(), database.Job{Kind: "test", Payload: []byte(`{}`)})
+	if !errors.Is(err, errTest) {
+		t.Errorf("got error %v; want %v", err, errTest)
+	}
+}
+
+func TestCallRecoversPanic(t *testing.T) {
+	q := newTestQueue()
+
+	Register(q, "test", func(ctx context.Context, args testArgs) error {
+		panic("boom")
+	})
+
+	err := q.call(context.Background(), database.Job{Kind: "test", Payload: []byte(`{}`)})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("got error %v; want an error holding the panic value", err)
+	}
+}
+
+func TestEnqueueUnknownKind(t *testing.T) {
+	q := newTestQueue()
+
+	_, err := q.Enqueue(context.Background(), "missing", testArgs{}, EnqueueOptions{})
+	if !errors.Is(err, ErrUnknownKind) {
+		t.Errorf("got error %v; want %v", err, ErrUnknownKind)
+	}
+}
+
+func TestCounts(t *testing.T) {
+	q := newTestQueue()
+
+	Register(q, "a", func(ctx context.Context, args testArgs) error { return nil })
+	Register(q, "b", func(ctx context.Context, args testArgs) error { return nil })
+
+	q.counters["a"].succeeded.Add(2)
+	q.counters["b"].retried.Add(1)
+	q.counters["b"].failed.Add(3)
+
+	counts := q.Counts()
+
+	want := map[string]Counts{
+		"a": {Succeeded: 2},
+		"b": {Retried: 1, Failed: 3},
+	}
+
+	if len(counts) != len(want) {
+		t.Fatalf("got counts for %d kinds; want %d", len(counts), len(want))
+	}
+
+	for kind, c := range want {
+		if counts[kind] != c {
+			t.Errorf("got %+v for %s; want %+v", counts[kind], kind, c)
+		}
+	}
+}
+
+// TestUniqueKeyConditions checks that the queries that rely on the partial
+// unique index on jobs.unique_key use the same condition as the index, so
+// that InsertJob's ON CONFLICT clause matches the index and RetryFailedJob
+// doesn't revive a job that would break it.
+func TestUniqueKeyConditions(t *testing.T) {
+	migrations, err := filepath.Glob("../../sql/schemas/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rgxIndex := regexp.MustCompile(`CREATE UNIQUE INDEX idx_jobs_unique_key ON jobs\(unique_key\) WHERE (.+);`)
+
+	var condition string
+	for _, migration := range migrations {
+		src, err := os.ReadFile(migration)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		up, _, _ := strings.Cut(string(src), "-- +goose Down")
+		if match := rgxIndex.FindStringSubmatch(up); match != nil {
+			condition = match[1]
+		}
+	}
+
+	if condition == "" {
+		t.Fatal("found no partial unique index on jobs.unique_key")
+	}
+
+	queries, err := os.ReadFile("../../sql/queries/jobs.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"ON CONFLICT (unique_key) WHERE " + condition,
+		"WHERE waiting.unique_key = jobs.unique_key AND waiting." + condition,
+	}
+
+	for _, clause := range want {
+		if !strings.Contains(string(queries), clause) {
+			t.Errorf("sql/queries/jobs.sql has no %q to match the index condition %q", clause, condition)
+		}
+	}
+}
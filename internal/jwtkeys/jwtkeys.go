@@ -0,0 +1,247 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/pascaldekloe/jwt"
+)
+
+var ErrNoKeys = errors.New("no keys found in PEM file")
+
+// KeySet signs tokens with a single active key and checks them against every
+// key that is currently trusted, so that signing keys can be rotated without
+// invalidating tokens issued under the previous key.
+type KeySet struct {
+	algorithm string
+	keyID     string
+	signer    any
+	register  jwt.KeyRegister
+	jwks      []JWK
+}
+
+// JWK is the public part of a signing key in RFC 7517 form.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// NewHMAC returns a key set that signs and checks tokens with a shared
+// HS256 secret. Its JWKS is always empty.
+func NewHMAC(secret []byte) *KeySet {
+	ks := &KeySet{
+		algorithm: jwt.HS256,
+		signer:    secret,
+	}
+	ks.register.Secrets = [][]byte{secret}
+
+	return ks
+}
+
+// Load returns a key set that signs with the Ed25519 or RSA private key in
+// signingKeyFile, and additionally trusts the public keys found in each of
+// verificationKeyFiles. Key IDs are the RFC 7638 thumbprints of the keys, and
+// a key that is listed more than once is only trusted once.
+func Load(signingKeyFile string, verificationKeyFiles ...string) (*KeySet, error) {
+	signingKeys, err := readPEMFile(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(signingKeys) > 1 {
+		return nil, fmt.Errorf("%s: signing key file must hold exactly one key, found %d", signingKeyFile, len(signingKeys))
+	}
+
+	ks := &KeySet{}
+
+	switch key := signingKeys[0].(type) {
+	case ed25519.PrivateKey:
+		ks.algorithm = jwt.EdDSA
+		ks.signer = key
+	case *rsa.PrivateKey:
+		ks.algorithm = jwt.RS256
+		ks.signer = key
+	default:
+		return nil, fmt.Errorf("%s: signing key must be an Ed25519 or RSA private key, got %T", signingKeyFile, key)
+	}
+
+	ks.keyID, err = ks.addKey(signingKeys[0])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range verificationKeyFiles {
+		keys, err := readPEMFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			_, err := ks.addKey(key)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+		}
+	}
+
+	return ks, nil
+}
+
+// Sign sets the key ID on the claims and returns the signed token.
+func (ks *KeySet) Sign(claims *jwt.Claims) ([]byte, error) {
+	claims.KeyID = ks.keyID
+
+	switch signer := ks.signer.(type) {
+	case ed25519.PrivateKey:
+		return claims.EdDSASign(signer)
+	case *rsa.PrivateKey:
+		return claims.RSASign(ks.algorithm, signer)
+	case []byte:
+		return claims.HMACSign(ks.algorithm, signer)
+	}
+
+	return nil, fmt.Errorf("unsupported signing key type %T", ks.signer)
+}
+
+// Check parses the token if, and only if, its signature matches one of the
+// trusted keys.
+func (ks *KeySet) Check(token []byte) (*jwt.Claims, error) {
+	return ks.register.Check(token)
+}
+
+// JWKS returns the public keys trusted by the key set, for publishing at
+// /.well-known/jwks.json.
+func (ks *KeySet) JWKS() []JWK {
+	jwks := make([]JWK, len(ks.jwks))
+	copy(jwks, ks.jwks)
+
+	return jwks
+}
+
+// addKey trusts the public part of key and returns its key ID. A key that is
+// already trusted is not added again, so that its ID appears only once in
+// the JWKS.
+func (ks *KeySet) addKey(key any) (string, error) {
+	var jwk JWK
+
+	switch key := key.(type) {
+	case ed25519.PrivateKey:
+		return ks.addKey(key.Public())
+	case *rsa.PrivateKey:
+		return ks.addKey(&key.PublicKey)
+	case ed25519.PublicKey:
+		jwk = JWK{
+			KeyType:   "OKP",
+			Algorithm: jwt.EdDSA,
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(key),
+		}
+		jwk.KeyID = thumbprint(map[string]string{"crv": jwk.Curve, "kty": jwk.KeyType, "x": jwk.X})
+
+		if ks.hasKey(jwk.KeyID) {
+			return jwk.KeyID, nil
+		}
+
+		ks.register.EdDSAs = append(ks.register.EdDSAs, key)
+		ks.register.EdDSAIDs = append(ks.register.EdDSAIDs, jwk.KeyID)
+	case *rsa.PublicKey:
+		jwk = JWK{
+			KeyType:   "RSA",
+			Algorithm: jwt.RS256,
+			N:         base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+		jwk.KeyID = thumbprint(map[string]string{"e": jwk.E, "kty": jwk.KeyType, "n": jwk.N})
+
+		if ks.hasKey(jwk.KeyID) {
+			return jwk.KeyID, nil
+		}
+
+		ks.register.RSAs = append(ks.register.RSAs, key)
+		ks.register.RSAIDs = append(ks.register.RSAIDs, jwk.KeyID)
+	default:
+		return "", fmt.Errorf("unsupported key type %T", key)
+	}
+
+	jwk.Use = "sig"
+	ks.jwks = append(ks.jwks, jwk)
+
+	return jwk.KeyID, nil
+}
+
+func (ks *KeySet) hasKey(keyID string) bool {
+	for _, jwk := range ks.jwks {
+		if jwk.KeyID == keyID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// thumbprint computes the RFC 7638 thumbprint of the required JWK members.
+// encoding/json sorts map keys, which gives the canonical member order.
+func thumbprint(members map[string]string) string {
+	js, _ := json.Marshal(members)
+	sum := sha256.Sum256(js)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func readPEMFile(path string) ([]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []any
+
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			break
+		}
+		data = rest
+
+		var key any
+
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "PRIVATE KEY":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		default:
+			return nil, fmt.Errorf("%s: unsupported PEM block type %q", path, block.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: %w", path, ErrNoKeys)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,256 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pascaldekloe/jwt"
+)
+
+func TestThumbprint(t *testing.T) {
+	// The RSA key is the example in RFC 7638 section 3.1, and the Ed25519 key
+	// the one in RFC 8037 appendix A.3.
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		key  any
+		want string
+	}{
+		{name: "RSA", key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}, want: "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"},
+		{name: "Ed25519", key: ed25519.PublicKey(x), want: "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ks KeySet
+
+			got, err := ks.addKey(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("got key ID %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// writePEM writes the blocks to a file in a temporary directory and returns
+// its path.
+func writePEM(t *testing.T, name string, blocks ...*pem.Block) string {
+	t.Helper()
+
+	var data []byte
+	for _, block := range blocks {
+		data = append(data, pem.EncodeToMemory(block)...)
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+
+	err := os.WriteFile(path, data, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func newEd25519Key(t *testing.T) (ed25519.PrivateKey, *pem.Block, *pem.Block) {
+	t.Helper()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return private, &pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}, &pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}
+}
+
+func TestLoad(t *testing.T) {
+	_, signing, signingPublic := newEd25519Key(t)
+	_, previous, previousPublic := newEd25519Key(t)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaSigning := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}
+
+	tests := []struct {
+		name          string
+		signing       []*pem.Block
+		verification  [][]*pem.Block
+		wantAlgorithm string
+		wantKeys      int
+		wantErr       bool
+	}{
+		{name: "Ed25519", signing: []*pem.Block{signing}, wantAlgorithm: jwt.EdDSA, wantKeys: 1},
+		{name: "RSA", signing: []*pem.Block{rsaSigning}, wantAlgorithm: jwt.RS256, wantKeys: 1},
+		{name: "Verification key", signing: []*pem.Block{signing}, verification: [][]*pem.Block{{previousPublic}}, wantAlgorithm: jwt.EdDSA, wantKeys: 2},
+		{name: "Verification private key", signing: []*pem.Block{signing}, verification: [][]*pem.Block{{previous}}, wantAlgorithm: jwt.EdDSA, wantKeys: 2},
+		{name: "Signing key listed for verification", signing: []*pem.Block{signing}, verification: [][]*pem.Block{{signingPublic, previousPublic}}, wantAlgorithm: jwt.EdDSA, wantKeys: 2},
+		{name: "Verification key listed twice", signing: []*pem.Block{signing}, verification: [][]*pem.Block{{previousPublic}, {previousPublic}}, wantAlgorithm: jwt.EdDSA, wantKeys: 2},
+		{name: "Two signing keys", signing: []*pem.Block{signing, previous}, wantErr: true},
+		{name: "Public signing key", signing: []*pem.Block{signingPublic}, wantErr: true},
+		{name: "Unsupported block", signing: []*pem.Block{{Type: "CERTIFICATE", Bytes: []byte("x")}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var verificationFiles []string
+			for _, blocks := range tt.verification {
+				verificationFiles = append(verificationFiles, writePEM(t, "verification.pem", blocks...))
+			}
+
+			ks, err := Load(writePEM(t, "signing.pem", tt.signing...), verificationFiles...)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("got nil error; want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ks.algorithm != tt.wantAlgorithm {
+				t.Errorf("got algorithm %s; want %s", ks.algorithm, tt.wantAlgorithm)
+			}
+
+			jwks := ks.JWKS()
+			if len(jwks) != tt.wantKeys {
+				t.Fatalf("got %d keys in the JWKS; want %d", len(jwks), tt.wantKeys)
+			}
+
+			if jwks[0].KeyID != ks.keyID {
+				t.Errorf("got %s first in the JWKS; want the signing key %s", jwks[0].KeyID, ks.keyID)
+			}
+
+			seen := map[string]bool{}
+			for _, jwk := range jwks {
+				if seen[jwk.KeyID] {
+					t.Errorf("got key ID %s more than once", jwk.KeyID)
+				}
+				seen[jwk.KeyID] = true
+
+				if jwk.Use != "sig" || jwk.Algorithm != tt.wantAlgorithm {
+					t.Errorf("got use %q and alg %q", jwk.Use, jwk.Algorithm)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadEmptyFile(t *testing.T) {
+	_, err := Load(writePEM(t, "signing.pem"))
+	if !errors.Is(err, ErrNoKeys) {
+		t.Errorf("got error %v; want %v", err, ErrNoKeys)
+	}
+}
+
+func TestSignAndCheck(t *testing.T) {
+	_, signing, _ := newEd25519Key(t)
+	_, previous, _ := newEd25519Key(t)
+
+	oldKeys, err := Load(writePEM(t, "previous.pem", previous))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The new key set signs with a new key and still trusts the previous one.
+	newKeys, err := Load(writePEM(t, "signing.pem", signing), writePEM(t, "previous.pem", previous))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKeys, err := Load(writePEM(t, "other.pem", signing))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, signer := range []*KeySet{oldKeys, newKeys} {
+		claims := jwt.Claims{}
+		claims.Subject = "alice"
+		claims.Expires = jwt.NewNumericTime(time.Now().Add(time.Minute))
+
+		token, err := signer.Sign(&claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		checked, err := newKeys.Check(token)
+		if err != nil {
+			t.Fatalf("got error %v checking a token signed with key %s", err, signer.keyID)
+		}
+
+		if checked.Subject != "alice" || checked.KeyID != signer.keyID {
+			t.Errorf("got subject %q and key ID %q; want alice and %s", checked.Subject, checked.KeyID, signer.keyID)
+		}
+	}
+
+	claims := jwt.Claims{}
+	token, err := oldKeys.Sign(&claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = otherKeys.Check(token)
+	if err == nil {
+		t.Error("got nil error checking a token signed with an untrusted key")
+	}
+}
+
+func TestHMAC(t *testing.T) {
+	ks := NewHMAC([]byte("secret"))
+
+	claims := jwt.Claims{}
+	claims.Subject = "alice"
+
+	token, err := ks.Sign(&claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checked, err := ks.Check(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if checked.Subject != "alice" {
+		t.Errorf("got subject %q; want alice", checked.Subject)
+	}
+
+	if len(ks.JWKS()) != 0 {
+		t.Errorf("got %d keys in the JWKS; want none", len(ks.JWKS()))
+	}
+}
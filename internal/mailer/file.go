@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileTransport writes each message to an .eml file in a directory, where
+// it can be opened with an email client.
+type FileTransport struct {
+	dir  string
+	from string
+}
+
+// NewFileTransport returns a FileTransport that writes to dir, creating it
+// if it doesn't exist.
+func NewFileTransport(dir, from string) (*FileTransport, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileTransport{dir: dir, from: from}, nil
+}
+
+func (t *FileTransport) Deliver(recipient string, message Message) error {
+	msg, err := NewMsg(t.from, recipient, message)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000Z"), uuid.NewString())
+
+	f, err := os.Create(filepath.Join(t.dir, name))
+	if err != nil {
+		return err
+	}
+
+	_, err = msg.WriteTo(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
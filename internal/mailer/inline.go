@@ -0,0 +1,304 @@
+package mailer
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	rgxStyle        = regexp.MustCompile(`(?is)<style[^>]*>(.*?)</style>\s*`)
+	rgxCSSComment   = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	rgxStartTag     = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9-]*)((?:\s+[^\s=/>]+(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'>]+))?)*)\s*(/?)>`)
+	rgxAttribute    = regexp.MustCompile(`\s+([^\s=/>]+)(?:\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+))?`)
+	rgxSimpleSelect = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9-]*)?((?:[.#][a-zA-Z_-][a-zA-Z0-9_-]*)*)$`)
+)
+
+// cssRule is a stylesheet rule with a single simple selector, such as p,
+// .code, #header or td.code.
+type cssRule struct {
+	tag          string
+	id           string
+	classes      []string
+	declarations []string
+	specificity  int
+}
+
+func (r cssRule) matches(tag string, attrs map[string]string) bool {
+	if r.tag != "" && !strings.EqualFold(r.tag, tag) {
+		return false
+	}
+
+	if r.id != "" && attrs["id"] != r.id {
+		return false
+	}
+
+	classes := strings.Fields(attrs["class"])
+	for _, class := range r.classes {
+		found := false
+		for _, c := range classes {
+			if c == class {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// stylesheet is a parsed stylesheet: the rules that can be inlined, sorted
+// by specificity, and the text of the rules that cannot. source is the CSS
+// it was parsed from.
+type stylesheet struct {
+	source string
+	rules  []cssRule
+	kept   string
+}
+
+func parseStylesheet(css string) *stylesheet {
+	rules, kept := parseCSS(css)
+	return &stylesheet{source: css, rules: rules, kept: kept}
+}
+
+// inlineCSS copies the rules in the document's <style> elements into the
+// style attribute of each element they match, because many email clients
+// ignore stylesheets. Declarations are applied in order of specificity, and
+// a style attribute already on an element wins over all of them.
+//
+// Only simple selectors are inlined: a tag name, an ID, classes, or a tag
+// with an ID or classes, and comma-separated lists of those. Rules with any
+// other selector, and at-rules such as @media, stay in a <style> element for
+// the clients that support them.
+//
+// sheet is used instead of parsing the document's stylesheet again when it
+// was parsed from the same CSS, and may be nil.
+func inlineCSS(doc string, sheet *stylesheet) string {
+	css := extractCSS(doc)
+	if css == "" {
+		return doc
+	}
+
+	if sheet == nil || sheet.source != css {
+		sheet = parseStylesheet(css)
+	}
+
+	rules, kept := sheet.rules, sheet.kept
+
+	// Replace the first <style> element with the rules that could not be
+	// inlined, and remove the others.
+	first := true
+	doc = rgxStyle.ReplaceAllStringFunc(doc, func(string) string {
+		if !first || kept == "" {
+			return ""
+		}
+		first = false
+		return "<style>\n" + kept + "</style>\n"
+	})
+
+	return rgxStartTag.ReplaceAllStringFunc(doc, func(tag string) string {
+		parts := rgxStartTag.FindStringSubmatch(tag)
+		name, rawAttrs, selfClosing := parts[1], parts[2], parts[3]
+
+		attrs := map[string]string{}
+		var otherAttrs strings.Builder
+		for _, attr := range rgxAttribute.FindAllStringSubmatch(rawAttrs, -1) {
+			key := strings.ToLower(attr[1])
+			attrs[key] = html.UnescapeString(strings.Trim(attr[2], `"'`))
+			if key != "style" {
+				otherAttrs.WriteString(attr[0])
+			}
+		}
+
+		var declarations []string
+		for _, rule := range rules {
+			if rule.matches(name, attrs) {
+				declarations = mergeDeclarations(declarations, rule.declarations)
+			}
+		}
+
+		if len(declarations) == 0 {
+			return tag
+		}
+
+		if style, ok := attrs["style"]; ok {
+			declarations = mergeDeclarations(declarations, splitDeclarations(style))
+		}
+
+		style := html.EscapeString(strings.Join(declarations, "; ") + ";")
+
+		return "<" + name + otherAttrs.String() + ` style="` + style + `"` + selfClosing + ">"
+	})
+}
+
+// extractCSS returns the contents of every <style> element in doc.
+func extractCSS(doc string) string {
+	var css strings.Builder
+	for _, match := range rgxStyle.FindAllStringSubmatch(doc, -1) {
+		css.WriteString(match[1])
+		css.WriteString("\n")
+	}
+
+	return css.String()
+}
+
+// parseCSS splits a stylesheet into the rules that can be inlined, sorted by
+// specificity, and the text of the rules that cannot.
+func parseCSS(css string) ([]cssRule, string) {
+	css = rgxCSSComment.ReplaceAllString(css, "")
+
+	var rules []cssRule
+	var kept strings.Builder
+
+	for {
+		css = strings.TrimSpace(css)
+		if css == "" {
+			break
+		}
+
+		open := strings.Index(css, "{")
+		if open < 0 {
+			break
+		}
+
+		end := blockEnd(css, open)
+		prelude := strings.TrimSpace(css[:open])
+		body := css[open+1 : end]
+		block := css[:min(end+1, len(css))]
+		css = css[min(end+1, len(css)):]
+
+		if strings.HasPrefix(prelude, "@") {
+			kept.WriteString(block + "\n")
+			continue
+		}
+
+		declarations := splitDeclarations(body)
+
+		var complex []string
+		for _, selector := range strings.Split(prelude, ",") {
+			selector = strings.TrimSpace(selector)
+
+			rule, ok := parseSelector(selector)
+			if !ok {
+				complex = append(complex, selector)
+				continue
+			}
+
+			rule.declarations = declarations
+			rules = append(rules, rule)
+		}
+
+		if len(complex) > 0 {
+			kept.WriteString(strings.Join(complex, ", ") + " {" + body + "}\n")
+		}
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].specificity < rules[j].specificity
+	})
+
+	return rules, kept.String()
+}
+
+// blockEnd returns the index of the brace that closes the block opened at
+// open, or the length of css if it is never closed.
+func blockEnd(css string, open int) int {
+	depth := 0
+	for i := open; i < len(css); i++ {
+		switch css[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return len(css)
+}
+
+func parseSelector(selector string) (cssRule, bool) {
+	match := rgxSimpleSelect.FindStringSubmatch(selector)
+	if match == nil || selector == "" {
+		return cssRule{}, false
+	}
+
+	rule := cssRule{tag: match[1]}
+	if rule.tag != "" {
+		rule.specificity = 1
+	}
+
+	rest := match[2]
+	for rest != "" {
+		next := strings.IndexAny(rest[1:], ".#") + 1
+		if next == 0 {
+			next = len(rest)
+		}
+
+		name := rest[1:next]
+		if rest[0] == '#' {
+			if rule.id != "" && rule.id != name {
+				return cssRule{}, false
+			}
+			rule.id = name
+			rule.specificity += 100
+		} else {
+			rule.classes = append(rule.classes, name)
+			rule.specificity += 10
+		}
+
+		rest = rest[next:]
+	}
+
+	return rule, true
+}
+
+func splitDeclarations(block string) []string {
+	var declarations []string
+	for _, declaration := range strings.Split(block, ";") {
+		property, value, ok := strings.Cut(declaration, ":")
+		if !ok {
+			continue
+		}
+
+		property = strings.ToLower(strings.TrimSpace(property))
+		value = strings.Join(strings.Fields(value), " ")
+		if property == "" || value == "" {
+			continue
+		}
+
+		declarations = append(declarations, property+": "+value)
+	}
+
+	return declarations
+}
+
+// mergeDeclarations appends add to declarations, dropping any earlier
+// declaration of a property that add sets again.
+func mergeDeclarations(declarations, add []string) []string {
+	for _, declaration := range add {
+		property, _, _ := strings.Cut(declaration, ":")
+
+		declarations = removeProperty(declarations, property)
+		declarations = append(declarations, declaration)
+	}
+
+	return declarations
+}
+
+func removeProperty(declarations []string, property string) []string {
+	kept := declarations[:0]
+	for _, declaration := range declarations {
+		if p, _, _ := strings.Cut(declaration, ":"); p != property {
+			kept = append(kept, declaration)
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,209 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInlineCSS(t *testing.T) {
+	tests := []struct {
+		name string
+		css  string
+		body string
+		want string
+	}{
+		{
+			name: "Tag selector",
+			css:  "p { margin: 0 }",
+			body: "<p>Hi</p>",
+			want: `<p style="margin: 0;">Hi</p>`,
+		},
+		{
+			name: "Class beats tag regardless of order",
+			css:  ".code { color: red } p { color: blue }",
+			body: `<p class="code">Hi</p>`,
+			want: `<p class="code" style="color: red;">Hi</p>`,
+		},
+		{
+			name: "ID beats class",
+			css:  "#main { color: red } .code { color: blue }",
+			body: `<p id="main" class="code">Hi</p>`,
+			want: `<p id="main" class="code" style="color: red;">Hi</p>`,
+		},
+		{
+			name: "Tag with class beats class",
+			css:  "p.code { color: red } .code { color: blue }",
+			body: `<p class="code">Hi</p>`,
+			want: `<p class="code" style="color: red;">Hi</p>`,
+		},
+		{
+			name: "Later rule wins at equal specificity",
+			css:  ".a { color: red } .b { color: blue }",
+			body: `<p class="a b">Hi</p>`,
+			want: `<p class="a b" style="color: blue;">Hi</p>`,
+		},
+		{
+			name: "Declarations from several rules are merged",
+			css:  "p { margin: 0; color: blue } .code { color: red }",
+			body: `<p class="code">Hi</p>`,
+			want: `<p class="code" style="margin: 0; color: red;">Hi</p>`,
+		},
+		{
+			name: "Existing style attribute wins",
+			css:  "#main.code { color: red; margin: 0 }",
+			body: `<p id="main" class="code" style="color: green">Hi</p>`,
+			want: `<p id="main" class="code" style="margin: 0; color: green;">Hi</p>`,
+		},
+		{
+			name: "Selector list",
+			css:  "h1, .title { font-weight: bold }",
+			body: `<h1>A</h1><span class="title">B</span>`,
+			want: `<h1 style="font-weight: bold;">A</h1><span class="title" style="font-weight: bold;">B</span>`,
+		},
+		{
+			name: "Class must be present",
+			css:  "p.code { color: red }",
+			body: `<p class="codes">Hi</p>`,
+			want: `<p class="codes">Hi</p>`,
+		},
+		{
+			name: "Single-quoted and unquoted attributes",
+			css:  ".code { color: red } #main { margin: 0 }",
+			body: `<p class='code'>A</p><div id=main>B</div>`,
+			want: `<p class='code' style="color: red;">A</p><div id=main style="margin: 0;">B</div>`,
+		},
+		{
+			name: "Self-closing tag",
+			css:  "img { border: 0 }",
+			body: `<img src="a.png" alt="A" />`,
+			want: `<img src="a.png" alt="A" style="border: 0;"/>`,
+		},
+		{
+			name: "Boolean attribute",
+			css:  "input { color: red }",
+			body: `<input disabled>`,
+			want: `<input disabled style="color: red;">`,
+		},
+		{
+			name: "Values are escaped",
+			css:  `p { font-family: "Segoe UI", sans-serif }`,
+			body: "<p>Hi</p>",
+			want: `<p style="font-family: &#34;Segoe UI&#34;, sans-serif;">Hi</p>`,
+		},
+		{
+			name: "Comments are ignored",
+			css:  "/* p { color: red } */ p { margin: 0 }",
+			body: "<p>Hi</p>",
+			want: `<p style="margin: 0;">Hi</p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := inlineCSS("<style>"+tt.css+"</style><body>"+tt.body+"</body>", nil)
+
+			want := "<body>" + tt.want + "</body>"
+			if got != want {
+				t.Errorf("got %s; want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestInlineCSSKeepsRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		css      string
+		wantKept string
+	}{
+		{
+			name:     "Media query",
+			css:      "p { margin: 0 } @media (max-width: 600px) { .container { padding: 16px } }",
+			wantKept: "@media (max-width: 600px) { .container { padding: 16px } }",
+		},
+		{
+			name:     "Descendant selector",
+			css:      ".container p { margin: 0 }",
+			wantKept: ".container p { margin: 0 }",
+		},
+		{
+			name:     "Pseudo-class in a selector list",
+			css:      "p, a:hover { color: red }",
+			wantKept: "a:hover { color: red }",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := inlineCSS("<head><style>"+tt.css+"</style></head><p>Hi</p>", nil)
+
+			if !strings.Contains(got, "<style>\n"+tt.wantKept+"\n</style>") {
+				t.Errorf("got %s; want a <style> element holding %q", got, tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestInlineCSSWithoutStylesheet(t *testing.T) {
+	doc := `<p class="code">Hi</p>`
+
+	if got := inlineCSS(doc, nil); got != doc {
+		t.Errorf("got %s; want the document unchanged", got)
+	}
+}
+
+func TestInlineCSSMergesStyleElements(t *testing.T) {
+	got := inlineCSS("<style>p { margin: 0 }</style><style>@media print { p { color: black } }</style><p>Hi</p>", nil)
+
+	want := "<style>\n@media print { p { color: black } }\n</style>\n<p style=\"margin: 0;\">Hi</p>"
+	if got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+
+func TestInlineCSSParsedStylesheet(t *testing.T) {
+	doc := "<style>p { margin: 0 }</style><p>Hi</p>"
+
+	sheet := parseStylesheet(extractCSS(doc))
+	sheet.rules[0].declarations = []string{"margin: 1px"}
+
+	if got := inlineCSS(doc, sheet); !strings.Contains(got, `style="margin: 1px;"`) {
+		t.Errorf("got %s; want the parsed stylesheet to be used", got)
+	}
+
+	other := parseStylesheet("p { color: red }")
+
+	if got := inlineCSS(doc, other); !strings.Contains(got, `style="margin: 0;"`) {
+		t.Errorf("got %s; want the document's own stylesheet to be used", got)
+	}
+}
+
+func TestNewParsesLayoutStylesheet(t *testing.T) {
+	m, err := New(NewMemoryTransport())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sheet := m.(*templateMailer).stylesheet
+	if len(sheet.rules) == 0 {
+		t.Fatal("got no rules from the layout stylesheet")
+	}
+
+	// The cached stylesheet is only used if it matches the CSS of a
+	// rendered layout exactly.
+	data, err := Fixture("email_confirmation")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc strings.Builder
+
+	err = m.(*templateMailer).templates["emails/email_confirmation.tmpl"].html.ExecuteTemplate(&doc, "htmlBody", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if css := extractCSS(doc.String()); css != sheet.source {
+		t.Errorf("got stylesheet %q in the rendered layout; want %q", css, sheet.source)
+	}
+}
@@ -0,0 +1,236 @@
+// Package mailer renders the email templates in assets/emails and hands the
+// result to a Transport, which delivers it. HTML bodies are rendered inside
+// the layout in assets/emails/layouts, with the layout's stylesheet inlined.
+// The SMTP transport lives in internal/smtp. This package has transports that
+// write .eml files, log messages, or keep them in memory, for development and
+// tests.
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"github.com/jcarloasilo/golang-rest-template/assets"
+	"github.com/jcarloasilo/golang-rest-template/internal/funcs"
+
+	"github.com/wneessen/go-mail"
+
+	htmlTemplate "html/template"
+	textTemplate "text/template"
+)
+
+// Mailer sends emails rendered from templates.
+type Mailer interface {
+	// Send renders the named template and sends the result to recipient.
+	Send(recipient, locale string, data any, name string) error
+
+	// Render executes the subject and plainBody templates from the named
+	// file, and the layout's htmlBody if the file defines content.
+	Render(locale string, data any, name string) (Message, error)
+
+	// SendMessage sends a rendered message to recipient.
+	SendMessage(recipient string, msg Message) error
+}
+
+// Transport delivers rendered messages.
+type Transport interface {
+	Deliver(recipient string, msg Message) error
+}
+
+// Message is a rendered email. HTMLBody is empty if the template has no
+// content.
+type Message struct {
+	Subject   string
+	PlainBody string
+	HTMLBody  string
+}
+
+// templateSet holds the parsed templates of one file. html also holds the
+// layouts and partials.
+type templateSet struct {
+	text *textTemplate.Template
+	html *htmlTemplate.Template
+}
+
+type templateMailer struct {
+	transport  Transport
+	templates  map[string]templateSet
+	stylesheet *stylesheet
+}
+
+// New returns a Mailer that renders templates from assets/emails and
+// delivers the result with transport. Every template and the layouts'
+// stylesheet are parsed once here, so a syntax error is returned rather than
+// found on the first Send.
+func New(transport Transport) (Mailer, error) {
+	shared, err := htmlTemplate.New("").Funcs(funcs.TemplateFuncs).ParseFS(assets.EmbeddedFiles, "emails/layouts/*.tmpl", "emails/partials/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := templateFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	css, err := layoutCSS()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &templateMailer{
+		transport:  transport,
+		templates:  make(map[string]templateSet, len(files)),
+		stylesheet: parseStylesheet(css),
+	}
+
+	for _, file := range files {
+		text, err := textTemplate.New("").Funcs(funcs.TemplateFuncs).ParseFS(assets.EmbeddedFiles, file)
+		if err != nil {
+			return nil, err
+		}
+
+		html, err := shared.Clone()
+		if err != nil {
+			return nil, err
+		}
+
+		html, err = html.ParseFS(assets.EmbeddedFiles, file)
+		if err != nil {
+			return nil, err
+		}
+
+		m.templates[file] = templateSet{text: text, html: html}
+	}
+
+	return m, nil
+}
+
+func (m *templateMailer) Send(recipient, locale string, data any, name string) error {
+	msg, err := m.Render(locale, data, name)
+	if err != nil {
+		return err
+	}
+
+	return m.SendMessage(recipient, msg)
+}
+
+// Render uses the template in emails/<locale>/ when a translation exists
+// there, and the one in emails/ otherwise.
+func (m *templateMailer) Render(locale string, data any, name string) (Message, error) {
+	ts, ok := m.templates[path.Join("emails", locale, name)]
+	if !ok {
+		ts, ok = m.templates[path.Join("emails", name)]
+	}
+	if !ok {
+		return Message{}, fmt.Errorf("mailer: no template named %q", name)
+	}
+
+	subject := new(bytes.Buffer)
+	err := ts.text.ExecuteTemplate(subject, "subject", data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	plainBody := new(bytes.Buffer)
+	err = ts.text.ExecuteTemplate(plainBody, "plainBody", data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	msg := Message{
+		Subject:   subject.String(),
+		PlainBody: plainBody.String(),
+	}
+
+	if ts.html.Lookup("content") != nil {
+		htmlBody := new(bytes.Buffer)
+		err = ts.html.ExecuteTemplate(htmlBody, "htmlBody", data)
+		if err != nil {
+			return Message{}, err
+		}
+
+		msg.HTMLBody = inlineCSS(htmlBody.String(), m.stylesheet)
+	}
+
+	return msg, nil
+}
+
+func (m *templateMailer) SendMessage(recipient string, msg Message) error {
+	return m.transport.Deliver(recipient, msg)
+}
+
+// NewMsg builds a MIME message from msg, for transports that send or store
+// complete emails.
+func NewMsg(from, recipient string, msg Message) (*mail.Msg, error) {
+	m := mail.NewMsg()
+
+	err := m.To(recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.From(from)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Subject(msg.Subject)
+	m.SetBodyString(mail.TypeTextPlain, msg.PlainBody)
+
+	if msg.HTMLBody != "" {
+		m.AddAlternativeString(mail.TypeTextHTML, msg.HTMLBody)
+	}
+
+	return m, nil
+}
+
+// templateFiles lists the email templates in emails/ and their translations
+// in emails/<locale>/, leaving out the shared layouts and partials.
+func templateFiles() ([]string, error) {
+	files, err := fs.Glob(assets.EmbeddedFiles, "emails/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	translations, err := fs.Glob(assets.EmbeddedFiles, "emails/*/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, translation := range translations {
+		if !isSharedDir(path.Base(path.Dir(translation))) {
+			files = append(files, translation)
+		}
+	}
+
+	return files, nil
+}
+
+// layoutCSS returns the contents of the <style> elements in the layouts, as
+// they appear in rendered emails. A stylesheet holding template actions
+// renders differently, and is then parsed on each render instead.
+func layoutCSS() (string, error) {
+	files, err := fs.Glob(assets.EmbeddedFiles, "emails/layouts/*.tmpl")
+	if err != nil {
+		return "", err
+	}
+
+	var css string
+	for _, file := range files {
+		src, err := fs.ReadFile(assets.EmbeddedFiles, file)
+		if err != nil {
+			return "", err
+		}
+
+		css += extractCSS(string(src))
+	}
+
+	return css, nil
+}
+
+func isSharedDir(dir string) bool {
+	return dir == "layouts" || dir == "partials"
+}
@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"slices"
+	"sync"
+)
+
+// SentMessage is a message kept by MemoryTransport.
+type SentMessage struct {
+	Recipient string
+	Message
+}
+
+// MemoryTransport keeps messages in memory, so tests can check what was
+// sent. Nothing is ever discarded, so it isn't offered as a MAIL_DRIVER.
+type MemoryTransport struct {
+	mu       sync.Mutex
+	messages []SentMessage
+}
+
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+func (t *MemoryTransport) Deliver(recipient string, message Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.messages = append(t.messages, SentMessage{Recipient: recipient, Message: message})
+	return nil
+}
+
+// Messages returns the messages delivered so far, oldest first.
+func (t *MemoryTransport) Messages() []SentMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return slices.Clone(t.messages)
+}
+
+// Reset forgets the messages delivered so far.
+func (t *MemoryTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.messages = nil
+}
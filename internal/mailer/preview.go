@@ -0,0 +1,130 @@
+package mailer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jcarloasilo/golang-rest-template/assets"
+)
+
+// Template describes an embedded email template. Locales lists the
+// translations in emails/<locale>/.
+type Template struct {
+	Name    string   `json:"name"`
+	Locales []string `json:"locales"`
+}
+
+// Templates lists the templates in emails/, sorted by name. The layouts and
+// partials in emails/layouts/ and emails/partials/ are not listed.
+func Templates() ([]Template, error) {
+	files, err := fs.Glob(assets.EmbeddedFiles, "emails/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	translations, err := fs.Glob(assets.EmbeddedFiles, "emails/*/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]Template, len(files))
+	for i, file := range files {
+		templates[i] = Template{
+			Name:    strings.TrimSuffix(path.Base(file), ".tmpl"),
+			Locales: []string{},
+		}
+
+		for _, translation := range translations {
+			dir := path.Base(path.Dir(translation))
+			if !isSharedDir(dir) && path.Base(translation) == path.Base(file) {
+				templates[i].Locales = append(templates[i].Locales, dir)
+			}
+		}
+	}
+
+	return templates, nil
+}
+
+// LookupTemplate returns the template with the given name.
+func LookupTemplate(name string) (Template, bool, error) {
+	templates, err := Templates()
+	if err != nil {
+		return Template{}, false, err
+	}
+
+	i := slices.IndexFunc(templates, func(t Template) bool { return t.Name == name })
+	if i < 0 {
+		return Template{}, false, nil
+	}
+
+	return templates[i], true, nil
+}
+
+// Fixture returns the sample data for the named template, which is read
+// from the .json file next to it. Strings holding RFC 3339 timestamps are
+// decoded as time.Time, so that templates can format them.
+func Fixture(name string) (map[string]any, error) {
+	js, err := fs.ReadFile(assets.EmbeddedFiles, path.Join("emails", name+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var data map[string]any
+
+	err = json.Unmarshal(js, &data)
+	if err != nil {
+		return nil, fmt.Errorf("fixture for %s: %w", name, err)
+	}
+
+	return parseTimes(data).(map[string]any), nil
+}
+
+func parseTimes(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			v[key] = parseTimes(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = parseTimes(value)
+		}
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err == nil {
+			return t
+		}
+	}
+
+	return v
+}
+
+// CheckTemplates renders every template and each of its translations with
+// its fixture, and returns the first error.
+func CheckTemplates(m Mailer) error {
+	templates, err := Templates()
+	if err != nil {
+		return err
+	}
+
+	for _, t := range templates {
+		data, err := Fixture(t.Name)
+		if err != nil {
+			return err
+		}
+
+		for _, locale := range append([]string{""}, t.Locales...) {
+			_, err = m.Render(locale, data, t.Name+".tmpl")
+			if err != nil {
+				return fmt.Errorf("email template %s (locale %q): %w", t.Name, locale, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTemplatesRender renders every template in the default locale and in
+// each of its translations with the template's fixture.
+func TestTemplatesRender(t *testing.T) {
+	m, err := New(NewMemoryTransport())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := Templates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(templates) == 0 {
+		t.Fatal("found no email templates")
+	}
+
+	for _, tmpl := range templates {
+		data, err := Fixture(tmpl.Name)
+		if err != nil {
+			t.Errorf("%s: %v", tmpl.Name, err)
+			continue
+		}
+
+		for _, locale := range append([]string{""}, tmpl.Locales...) {
+			name := locale
+			if name == "" {
+				name = "default"
+			}
+
+			t.Run(tmpl.Name+"/"+name, func(t *testing.T) {
+				msg, err := m.Render(locale, data, tmpl.Name+".tmpl")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if strings.TrimSpace(msg.Subject) == "" {
+					t.Error("got an empty subject")
+				}
+
+				if strings.TrimSpace(msg.PlainBody) == "" {
+					t.Error("got an empty plain text body")
+				}
+
+				if strings.TrimSpace(msg.HTMLBody) == "" {
+					t.Error("got an empty HTML body")
+				}
+			})
+		}
+	}
+}
+
+func TestFixtureParsesTimes(t *testing.T) {
+	data := parseTimes(map[string]any{
+		"At":    "2026-01-02T15:04:05Z",
+		"Items": []any{"2026-01-02T15:04:05+01:00"},
+		"Name":  "Alice",
+	}).(map[string]any)
+
+	tests := []struct {
+		name    string
+		value   any
+		wantStr bool
+	}{
+		{name: "Top-level timestamp", value: data["At"]},
+		{name: "Timestamp in a list", value: data["Items"].([]any)[0]},
+		{name: "Plain string", value: data["Name"], wantStr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, isStr := tt.value.(string)
+			if isStr != tt.wantStr {
+				t.Errorf("got %T; want string: %t", tt.value, tt.wantStr)
+			}
+		})
+	}
+}
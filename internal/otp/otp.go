@@ -0,0 +1,211 @@
+// Package otp issues and verifies the one-time passwords that are emailed to
+// users. Each OTP type has its own Policy. Codes are stored as HMAC-SHA256
+// hashes, and a code is consumed by the same atomic operation that checks
+// it, so it can never be used twice.
+package otp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	Digits       = "0123456789"
+	AlphaNumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+var (
+	ErrNotFound        = errors.New("otp: not found")
+	ErrExpired         = errors.New("otp: expired")
+	ErrTooManyAttempts = errors.New("otp: too many failed attempts")
+	ErrInvalid         = errors.New("otp: invalid code")
+)
+
+// CooldownError is returned by CheckCooldown when the previous OTP of the
+// same type was issued less than the policy's ResendCooldown ago.
+type CooldownError struct {
+	RetryAfter time.Duration
+}
+
+func (e *CooldownError) Error() string {
+	return fmt.Sprintf("otp: a new code can be issued in %s", e.RetryAfter.Round(time.Second))
+}
+
+// Policy controls the OTPs of one type. A zero ResendCooldown allows a new
+// code to be issued at any time.
+type Policy struct {
+	Length         int
+	Alphabet       string
+	TTL            time.Duration
+	MaxAttempts    int
+	ResendCooldown time.Duration
+}
+
+// Service issues and verifies OTPs according to a policy per OTP type.
+type Service struct {
+	store     Store
+	policies  map[database.OtpType]Policy
+	secretKey []byte
+	now       func() time.Time
+}
+
+// NewService returns a Service that keeps OTPs in store and hashes their
+// codes with secretKey. Every OTP type that is used must have a policy.
+func NewService(store Store, secretKey string, policies map[database.OtpType]Policy) *Service {
+	return &Service{
+		store:     store,
+		policies:  policies,
+		secretKey: []byte(secretKey),
+		now:       time.Now,
+	}
+}
+
+// WithStore returns a copy of the service that uses store, such as a
+// PostgresStore bound to a transaction.
+func (s *Service) WithStore(store Store) *Service {
+	clone := *s
+	clone.store = store
+	return &clone
+}
+
+// CheckCooldown returns a *CooldownError if the user's latest OTP of the
+// given type was issued less than the policy's ResendCooldown ago.
+func (s *Service) CheckCooldown(ctx context.Context, userID uuid.UUID, otpType database.OtpType) error {
+	policy, err := s.policy(otpType)
+	if err != nil {
+		return err
+	}
+
+	if policy.ResendCooldown <= 0 {
+		return nil
+	}
+
+	record, err := s.store.Latest(ctx, userID, otpType)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if wait := record.CreatedAt.Add(policy.ResendCooldown).Sub(s.now()); wait > 0 {
+		return &CooldownError{RetryAfter: wait}
+	}
+
+	return nil
+}
+
+// HasUsable reports whether the user has an OTP of the given type that can
+// still be used: one that hasn't been consumed, expired or run out of
+// attempts.
+func (s *Service) HasUsable(ctx context.Context, userID uuid.UUID, otpType database.OtpType) (bool, error) {
+	record, err := s.store.Latest(ctx, userID, otpType)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return record.ExpiresAt.After(s.now()) && record.Attempts < record.MaxAttempts, nil
+}
+
+// Issue replaces any existing OTP of the given type for the user with a newly
+// generated one, and returns its code. It doesn't check the resend cooldown,
+// which callers do with CheckCooldown when the code is requested.
+func (s *Service) Issue(ctx context.Context, userID uuid.UUID, otpType database.OtpType) (string, error) {
+	policy, err := s.policy(otpType)
+	if err != nil {
+		return "", err
+	}
+
+	now := s.now()
+
+	code, err := generate(policy.Length, policy.Alphabet)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.store.Replace(ctx, Record{
+		UserID:      userID,
+		Type:        otpType,
+		CodeHash:    s.Hash(code),
+		MaxAttempts: policy.MaxAttempts,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(policy.TTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// Verify checks code against the user's latest OTP of the given type and
+// consumes the OTP if it matches. A wrong code counts as a failed attempt.
+// It returns ErrNotFound, ErrExpired, ErrTooManyAttempts or ErrInvalid if
+// the code isn't accepted.
+func (s *Service) Verify(ctx context.Context, userID uuid.UUID, otpType database.OtpType, code string) error {
+	res, err := s.store.Consume(ctx, userID, otpType, s.Hash(code))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case res.Consumed:
+		return nil
+	case res.Expired:
+		return ErrExpired
+	case res.Exhausted:
+		return ErrTooManyAttempts
+	default:
+		return ErrInvalid
+	}
+}
+
+// Invalidate deletes the user's OTPs of the given type.
+func (s *Service) Invalidate(ctx context.Context, userID uuid.UUID, otpType database.OtpType) error {
+	return s.store.Delete(ctx, userID, otpType)
+}
+
+// Hash returns the HMAC-SHA256 of code keyed with the secret key. Codes are
+// short enough to brute force, so a plain hash would not protect them.
+func (s *Service) Hash(code string) []byte {
+	mac := hmac.New(sha256.New, s.secretKey)
+	mac.Write([]byte(code))
+	return mac.Sum(nil)
+}
+
+func (s *Service) policy(otpType database.OtpType) (Policy, error) {
+	policy, ok := s.policies[otpType]
+	if !ok {
+		return Policy{}, fmt.Errorf("otp: no policy for type %q", otpType)
+	}
+
+	return policy, nil
+}
+
+func generate(length int, alphabet string) (string, error) {
+	max := big.NewInt(int64(len(alphabet)))
+	code := make([]byte, length)
+
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = alphabet[n.Int64()]
+	}
+
+	return string(code), nil
+}
@@ -0,0 +1,380 @@
+package otp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// memoryStore is a Store that keeps the latest OTP of each user and type in
+// memory, behaving like the queries behind PostgresStore.
+type memoryStore struct {
+	now     func() time.Time
+	records map[memoryKey]*memoryRecord
+}
+
+type memoryKey struct {
+	userID  uuid.UUID
+	otpType database.OtpType
+}
+
+type memoryRecord struct {
+	Record
+	attempts int
+}
+
+func newMemoryStore(now func() time.Time) *memoryStore {
+	return &memoryStore{now: now, records: map[memoryKey]*memoryRecord{}}
+}
+
+func (s *memoryStore) Latest(ctx context.Context, userID uuid.UUID, otpType database.OtpType) (Record, error) {
+	record, ok := s.records[memoryKey{userID, otpType}]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+
+	latest := record.Record
+	latest.Attempts = record.attempts
+	return latest, nil
+}
+
+func (s *memoryStore) Replace(ctx context.Context, record Record) error {
+	s.records[memoryKey{record.UserID, record.Type}] = &memoryRecord{Record: record}
+	return nil
+}
+
+func (s *memoryStore) Consume(ctx context.Context, userID uuid.UUID, otpType database.OtpType, codeHash []byte) (ConsumeResult, error) {
+	key := memoryKey{userID, otpType}
+
+	record, ok := s.records[key]
+	if !ok {
+		return ConsumeResult{}, ErrNotFound
+	}
+
+	res := ConsumeResult{
+		Expired:   !record.ExpiresAt.After(s.now()),
+		Exhausted: record.attempts >= record.MaxAttempts,
+	}
+
+	switch {
+	case res.Expired || res.Exhausted:
+	case bytes.Equal(record.CodeHash, codeHash):
+		delete(s.records, key)
+		res.Consumed = true
+	default:
+		record.attempts++
+	}
+
+	return res, nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, userID uuid.UUID, otpType database.OtpType) error {
+	delete(s.records, memoryKey{userID, otpType})
+	return nil
+}
+
+var testPolicy = Policy{
+	Length:         6,
+	Alphabet:       Digits,
+	TTL:            5 * time.Minute,
+	MaxAttempts:    3,
+	ResendCooldown: time.Minute,
+}
+
+// newTestService returns a Service with a memory store and a clock that only
+// moves when the returned function is called.
+func newTestService() (*Service, func(time.Duration)) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	s := NewService(newMemoryStore(clock), "secret", map[database.OtpType]Policy{
+		database.OtpTypeTwoFactorAuth: testPolicy,
+	})
+	s.now = clock
+
+	return s, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestIssue(t *testing.T) {
+	s, _ := newTestService()
+
+	code, err := s.Issue(context.Background(), uuid.New(), database.OtpTypeTwoFactorAuth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(code) != testPolicy.Length {
+		t.Errorf("got code of length %d; want %d", len(code), testPolicy.Length)
+	}
+
+	if strings.Trim(code, Digits) != "" {
+		t.Errorf("got code %q; want only digits", code)
+	}
+}
+
+func TestIssueWithoutPolicy(t *testing.T) {
+	s, _ := newTestService()
+
+	_, err := s.Issue(context.Background(), uuid.New(), database.OtpTypePasswordReset)
+	if err == nil {
+		t.Error("got nil error; want an error for a type without a policy")
+	}
+}
+
+func TestCheckCooldown(t *testing.T) {
+	tests := []struct {
+		name      string
+		issue     bool
+		wait      time.Duration
+		wantRetry time.Duration
+	}{
+		{name: "Never issued"},
+		{name: "Immediately", issue: true, wait: 0, wantRetry: time.Minute},
+		{name: "During cooldown", issue: true, wait: 40 * time.Second, wantRetry: 20 * time.Second},
+		{name: "After cooldown", issue: true, wait: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, advance := newTestService()
+			userID := uuid.New()
+
+			if tt.issue {
+				_, err := s.Issue(context.Background(), userID, database.OtpTypeTwoFactorAuth)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			advance(tt.wait)
+
+			err := s.CheckCooldown(context.Background(), userID, database.OtpTypeTwoFactorAuth)
+
+			var cooldown *CooldownError
+			switch {
+			case tt.wantRetry == 0 && err != nil:
+				t.Errorf("got error %v; want nil", err)
+			case tt.wantRetry != 0 && !errors.As(err, &cooldown):
+				t.Errorf("got error %v; want a *CooldownError", err)
+			case tt.wantRetry != 0 && cooldown.RetryAfter != tt.wantRetry:
+				t.Errorf("got RetryAfter %s; want %s", cooldown.RetryAfter, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestHasUsable(t *testing.T) {
+	tests := []struct {
+		name    string
+		issue   bool
+		wrong   int
+		consume bool
+		wait    time.Duration
+		want    bool
+	}{
+		{name: "Never issued"},
+		{name: "Issued", issue: true, want: true},
+		{name: "Attempts left", issue: true, wrong: testPolicy.MaxAttempts - 1, want: true},
+		{name: "Attempts exhausted", issue: true, wrong: testPolicy.MaxAttempts},
+		{name: "Consumed", issue: true, consume: true},
+		{name: "Expired", issue: true, wait: testPolicy.TTL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, advance := newTestService()
+			userID := uuid.New()
+
+			if tt.issue {
+				code, err := s.Issue(context.Background(), userID, database.OtpTypeTwoFactorAuth)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for range tt.wrong {
+					_ = s.Verify(context.Background(), userID, database.OtpTypeTwoFactorAuth, "wrong")
+				}
+
+				if tt.consume {
+					err = s.Verify(context.Background(), userID, database.OtpTypeTwoFactorAuth, code)
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			advance(tt.wait)
+
+			got, err := s.HasUsable(context.Background(), userID, database.OtpTypeTwoFactorAuth)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		wait    time.Duration
+		wrong   int
+		correct bool
+		wantErr error
+	}{
+		{name: "Correct code", correct: true},
+		{name: "Wrong code", wantErr: ErrInvalid},
+		{name: "Correct after wrong codes", wrong: testPolicy.MaxAttempts - 1, correct: true},
+		{name: "Correct after attempts exhausted", wrong: testPolicy.MaxAttempts, correct: true, wantErr: ErrTooManyAttempts},
+		{name: "Wrong after attempts exhausted", wrong: testPolicy.MaxAttempts, wantErr: ErrTooManyAttempts},
+		{name: "Correct just before expiry", wait: testPolicy.TTL - time.Second, correct: true},
+		{name: "Correct after expiry", wait: testPolicy.TTL, correct: true, wantErr: ErrExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, advance := newTestService()
+			userID := uuid.New()
+
+			code, err := s.Issue(context.Background(), userID, database.OtpTypeTwoFactorAuth)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for range tt.wrong {
+				err = s.Verify(context.Background(), userID, database.OtpTypeTwoFactorAuth, "wrong")
+				if !errors.Is(err, ErrInvalid) {
+					t.Fatalf("got error %v for a wrong code; want %v", err, ErrInvalid)
+				}
+			}
+
+			advance(tt.wait)
+
+			attempt := "wrong"
+			if tt.correct {
+				attempt = code
+			}
+
+			err = s.Verify(context.Background(), userID, database.OtpTypeTwoFactorAuth, attempt)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v; want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifySingleUse(t *testing.T) {
+	s, _ := newTestService()
+	userID := uuid.New()
+
+	code, err := s.Issue(context.Background(), userID, database.OtpTypeTwoFactorAuth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Verify(context.Background(), userID, database.OtpTypeTwoFactorAuth, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Verify(context.Background(), userID, database.OtpTypeTwoFactorAuth, code)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v on reuse; want %v", err, ErrNotFound)
+	}
+}
+
+func TestVerifyReplacedCode(t *testing.T) {
+	s, advance := newTestService()
+	userID := uuid.New()
+
+	oldCode, err := s.Issue(context.Background(), userID, database.OtpTypeTwoFactorAuth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	advance(testPolicy.ResendCooldown)
+
+	newCode, err := s.Issue(context.Background(), userID, database.OtpTypeTwoFactorAuth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if oldCode != newCode {
+		err = s.Verify(context.Background(), userID, database.OtpTypeTwoFactorAuth, oldCode)
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("got error %v for the replaced code; want %v", err, ErrInvalid)
+		}
+	}
+
+	err = s.Verify(context.Background(), userID, database.OtpTypeTwoFactorAuth, newCode)
+	if err != nil {
+		t.Errorf("got error %v for the new code; want nil", err)
+	}
+}
+
+func TestVerifyOtherTypeOrUser(t *testing.T) {
+	s, _ := newTestService()
+	userID := uuid.New()
+
+	code, err := s.Issue(context.Background(), userID, database.OtpTypeTwoFactorAuth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Verify(context.Background(), uuid.New(), database.OtpTypeTwoFactorAuth, code)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v for another user; want %v", err, ErrNotFound)
+	}
+
+	err = s.Verify(context.Background(), userID, database.OtpTypeEmailChange, code)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v for another type; want %v", err, ErrNotFound)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	s, _ := newTestService()
+	userID := uuid.New()
+
+	code, err := s.Issue(context.Background(), userID, database.OtpTypeTwoFactorAuth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Invalidate(context.Background(), userID, database.OtpTypeTwoFactorAuth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Verify(context.Background(), userID, database.OtpTypeTwoFactorAuth, code)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v after Invalidate; want %v", err, ErrNotFound)
+	}
+}
+
+func TestHash(t *testing.T) {
+	a := NewService(nil, "key-a", nil)
+	b := NewService(nil, "key-b", nil)
+
+	if !bytes.Equal(a.Hash("123456"), a.Hash("123456")) {
+		t.Error("got different hashes for the same code and key")
+	}
+
+	if bytes.Equal(a.Hash("123456"), a.Hash("654321")) {
+		t.Error("got the same hash for different codes")
+	}
+
+	if bytes.Equal(a.Hash("123456"), b.Hash("123456")) {
+		t.Error("got the same hash for different keys")
+	}
+}
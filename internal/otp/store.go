@@ -0,0 +1,120 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Record is an OTP as it is stored. Attempts counts the wrong codes tried so
+// far, and is ignored by Replace.
+type Record struct {
+	UserID      uuid.UUID
+	Type        database.OtpType
+	CodeHash    []byte
+	Attempts    int
+	MaxAttempts int
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// ConsumeResult describes the OTP that Store.Consume looked at. Consumed is
+// only true if the hash matched and the OTP could still be used.
+type ConsumeResult struct {
+	Consumed  bool
+	Expired   bool
+	Exhausted bool
+}
+
+// Store holds OTPs. Replace and Consume must each be atomic.
+type Store interface {
+	// Latest returns the user's latest OTP of the type, or ErrNotFound.
+	Latest(ctx context.Context, userID uuid.UUID, otpType database.OtpType) (Record, error)
+
+	// Replace deletes the user's OTPs of the record's type and stores the
+	// record in their place.
+	Replace(ctx context.Context, record Record) error
+
+	// Consume finds the user's latest OTP of the type, or returns
+	// ErrNotFound. If it is still usable it is deleted when codeHash matches,
+	// and has its attempts incremented otherwise.
+	Consume(ctx context.Context, userID uuid.UUID, otpType database.OtpType, codeHash []byte) (ConsumeResult, error)
+
+	// Delete deletes the user's OTPs of the type.
+	Delete(ctx context.Context, userID uuid.UUID, otpType database.OtpType) error
+}
+
+// PostgresStore keeps OTPs in the otps table.
+type PostgresStore struct {
+	db *database.Queries
+}
+
+func NewPostgresStore(db *database.Queries) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Latest(ctx context.Context, userID uuid.UUID, otpType database.OtpType) (Record, error) {
+	otp, err := s.db.GetLatestOTP(ctx, database.GetLatestOTPParams{
+		UserID: userID,
+		Type:   otpType,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Record{}, ErrNotFound
+		}
+		return Record{}, err
+	}
+
+	return Record{
+		UserID:      userID,
+		Type:        otp.Type,
+		CodeHash:    otp.CodeHash,
+		Attempts:    int(otp.Attempts),
+		MaxAttempts: int(otp.MaxAttempts),
+		CreatedAt:   otp.CreatedAt,
+		ExpiresAt:   otp.ExpiresAt,
+	}, nil
+}
+
+func (s *PostgresStore) Replace(ctx context.Context, record Record) error {
+	return s.db.ReplaceOTP(ctx, database.ReplaceOTPParams{
+		CodeHash:    record.CodeHash,
+		Type:        record.Type,
+		ExpiresAt:   record.ExpiresAt,
+		CreatedAt:   record.CreatedAt,
+		MaxAttempts: int32(record.MaxAttempts),
+		UserID:      record.UserID,
+	})
+}
+
+func (s *PostgresStore) Consume(ctx context.Context, userID uuid.UUID, otpType database.OtpType, codeHash []byte) (ConsumeResult, error) {
+	row, err := s.db.ConsumeOTP(ctx, database.ConsumeOTPParams{
+		UserID:   userID,
+		Type:     otpType,
+		CodeHash: codeHash,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ConsumeResult{}, ErrNotFound
+		}
+		return ConsumeResult{}, err
+	}
+
+	return ConsumeResult{
+		Consumed:  row.Consumed,
+		Expired:   row.Expired,
+		Exhausted: row.Exhausted,
+	}, nil
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, userID uuid.UUID, otpType database.OtpType) error {
+	return s.db.InvalidateExistingOTP(ctx, database.InvalidateExistingOTPParams{
+		UserID: userID,
+		Type:   otpType,
+	})
+}
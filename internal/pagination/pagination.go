@@ -0,0 +1,227 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/validator"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+	MaxPage         = 10_000_000
+)
+
+// Options describes which query string values a list endpoint accepts.
+// CursorSortSafelist lists the sort values that support cursor pagination;
+// when it is empty the endpoint only supports page numbers.
+type Options struct {
+	DefaultSort        string
+	SortSafelist       []string
+	CursorSortSafelist []string
+	FilterSafelist     []string
+}
+
+// Params holds the pagination, sorting and filtering parameters of a list
+// request. Exactly one of Page and Cursor is in use: Cursor is set when the
+// client sent ?cursor=, in which case Page is zero. CursorSupported reports
+// whether Sort is in the CursorSortSafelist, in which case every page,
+// including one requested by number, should return a next cursor.
+type Params struct {
+	Page            int
+	PageSize        int
+	Cursor          string
+	Sort            string
+	CursorSupported bool
+	Filters         map[string]string
+}
+
+// Parse reads ?page, ?page_size, ?cursor, ?sort and ?filter[name] from the
+// query string, recording any problems in v.
+func Parse(v *validator.Validator, qs url.Values, opts Options) Params {
+	p := Params{
+		Page:     1,
+		PageSize: DefaultPageSize,
+		Cursor:   qs.Get("cursor"),
+		Sort:     opts.DefaultSort,
+		Filters:  map[string]string{},
+	}
+
+	if s := qs.Get("page"); s != "" {
+		page, err := strconv.Atoi(s)
+		v.CheckField(err == nil, "page", "value.not_integer", "Must be an integer value")
+		v.CheckField(err != nil || validator.Between(page, 1, MaxPage), "page", "value.out_of_range", fmt.Sprintf("Must be between 1 and %d", MaxPage), "min", 1, "max", MaxPage)
+		v.CheckField(p.Cursor == "", "page", "page.conflicts_with_cursor", "Cannot be combined with cursor")
+		p.Page = page
+	}
+
+	if s := qs.Get("page_size"); s != "" {
+		pageSize, err := strconv.Atoi(s)
+		v.CheckField(err == nil, "page_size", "value.not_integer", "Must be an integer value")
+		v.CheckField(err != nil || validator.Between(pageSize, 1, MaxPageSize), "page_size", "value.out_of_range", fmt.Sprintf("Must be between 1 and %d", MaxPageSize), "min", 1, "max", MaxPageSize)
+		p.PageSize = pageSize
+	}
+
+	if s := qs.Get("sort"); s != "" {
+		v.CheckField(validator.In(s, opts.SortSafelist...), "sort", "sort.invalid", "Invalid sort value")
+		p.Sort = s
+	}
+
+	p.CursorSupported = validator.In(p.Sort, opts.CursorSortSafelist...)
+
+	if p.Cursor != "" {
+		v.CheckField(p.CursorSupported, "cursor", "cursor.unsupported_sort", "Cursor pagination is not supported for this sort order")
+		p.Page = 0
+	}
+
+	for key, values := range qs {
+		name, ok := filterName(key)
+		if !ok {
+			continue
+		}
+
+		v.CheckField(validator.In(name, opts.FilterSafelist...), key, "filter.unknown", "Unknown filter")
+		p.Filters[name] = values[0]
+	}
+
+	return p
+}
+
+func filterName(key string) (string, bool) {
+	if !strings.HasPrefix(key, "filter[") || !strings.HasSuffix(key, "]") {
+		return "", false
+	}
+
+	return key[len("filter[") : len(key)-1], true
+}
+
+// Filter returns the value of the named filter, or nil if it wasn't given.
+func (p Params) Filter(name string) *string {
+	value, ok := p.Filters[name]
+	if !ok || value == "" {
+		return nil
+	}
+
+	return &value
+}
+
+func (p Params) Limit() int32 {
+	return int32(p.PageSize)
+}
+
+func (p Params) Offset() int32 {
+	if p.Cursor != "" {
+		return 0
+	}
+
+	return int32((p.Page - 1) * p.PageSize)
+}
+
+// Metadata describes the page being returned. Page numbers are only set for
+// offset pagination, where TotalRecords counts every record, and otherwise
+// TotalRecords counts the records from the cursor onwards. NextCursor is set
+// whenever the sort order supports cursors and there is a next page.
+type Metadata struct {
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int64  `json:"total_records"`
+	NextCursor   string `json:"next_cursor,omitempty"`
+}
+
+func NewMetadata(p Params, totalRecords int64, nextCursor string) Metadata {
+	m := Metadata{
+		PageSize:     p.PageSize,
+		TotalRecords: totalRecords,
+		NextCursor:   nextCursor,
+	}
+
+	if p.Cursor == "" {
+		m.CurrentPage = p.Page
+		m.FirstPage = 1
+		m.LastPage = max(1, int(math.Ceil(float64(totalRecords)/float64(p.PageSize))))
+	}
+
+	return m
+}
+
+// Envelope is the standard response body of a list endpoint.
+type Envelope struct {
+	Data     any      `json:"data"`
+	Metadata Metadata `json:"metadata"`
+}
+
+// LinkHeader returns an RFC 8288 Link header value pointing at the first,
+// previous, next and last pages relative to the request URL u.
+func LinkHeader(u *url.URL, m Metadata) string {
+	var links []string
+
+	link := func(rel string, set func(url.Values)) {
+		qs := u.Query()
+		qs.Del("page")
+		qs.Del("cursor")
+		set(qs)
+
+		target := *u
+		target.RawQuery = qs.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, target.String(), rel))
+	}
+
+	page := func(n int) func(url.Values) {
+		return func(qs url.Values) { qs.Set("page", strconv.Itoa(n)) }
+	}
+
+	if m.CurrentPage == 0 {
+		link("first", func(url.Values) {})
+		if m.NextCursor != "" {
+			link("next", func(qs url.Values) { qs.Set("cursor", m.NextCursor) })
+		}
+		return strings.Join(links, ", ")
+	}
+
+	link("first", page(m.FirstPage))
+	if m.CurrentPage > m.FirstPage {
+		link("prev", page(min(m.CurrentPage-1, m.LastPage)))
+	}
+	if m.CurrentPage < m.LastPage {
+		link("next", page(m.CurrentPage+1))
+	}
+	link("last", page(m.LastPage))
+
+	return strings.Join(links, ", ")
+}
+
+// EncodeCursor packs the sort key values of the last row on a page into an
+// opaque cursor string.
+func EncodeCursor(values ...string) string {
+	js, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// DecodeCursor unpacks a cursor created by EncodeCursor, checking that it
+// holds n values.
+func DecodeCursor(cursor string, n int) ([]string, error) {
+	js, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	err = json.Unmarshal(js, &values)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) != n {
+		return nil, fmt.Errorf("cursor has %d values, expected %d", len(values), n)
+	}
+
+	return values, nil
+}
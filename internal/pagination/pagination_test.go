@@ -0,0 +1,300 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/validator"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := [][]string{
+		{"2026-01-02T15:04:05.123456789Z", "0b3e1a4c-5d6f-4a7b-8c9d-0e1f2a3b4c5d"},
+		{""},
+		{"a,b", `"quoted"`, "ünïcode", "+/="},
+	}
+
+	for _, values := range tests {
+		cursor := EncodeCursor(values...)
+
+		got, err := DecodeCursor(cursor, len(values))
+		if err != nil {
+			t.Fatalf("got error %v decoding %q", err, cursor)
+		}
+
+		if !slices.Equal(got, values) {
+			t.Errorf("got %q; want %q", got, values)
+		}
+
+		if url.QueryEscape(cursor) != cursor {
+			t.Errorf("got cursor %q; want one that needs no escaping in a query string", cursor)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	valid := EncodeCursor("2026-01-02T15:04:05Z", "0b3e1a4c-5d6f-4a7b-8c9d-0e1f2a3b4c5d")
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "Garbage", cursor: "not a cursor!"},
+		{name: "Truncated", cursor: valid[:len(valid)/2]},
+		{name: "Padded", cursor: valid + "=="},
+		{name: "Standard encoding", cursor: base64.StdEncoding.EncodeToString([]byte(`["a?>","b"]`))},
+		{name: "Not JSON", cursor: base64.RawURLEncoding.EncodeToString([]byte("a,b"))},
+		{name: "Not an array", cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"a":"b"}`))},
+		{name: "Not strings", cursor: base64.RawURLEncoding.EncodeToString([]byte(`[1,2]`))},
+		{name: "Too few values", cursor: EncodeCursor("a")},
+		{name: "Too many values", cursor: EncodeCursor("a", "b", "c")},
+		{name: "Empty", cursor: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(tt.cursor, 2)
+			if err == nil {
+				t.Errorf("got %q; want an error", got)
+			}
+		})
+	}
+}
+
+var testOptions = Options{
+	DefaultSort:        "-created_at",
+	SortSafelist:       []string{"created_at", "-created_at", "email", "-email"},
+	CursorSortSafelist: []string{"created_at", "-created_at"},
+	FilterSafelist:     []string{"email"},
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name            string
+		qs              string
+		wantPage        int
+		wantPageSize    int
+		wantCursor      string
+		wantSort        string
+		wantCursorOK    bool
+		wantFieldErrors []string
+	}{
+		{name: "Defaults", qs: "", wantPage: 1, wantPageSize: DefaultPageSize, wantSort: "-created_at", wantCursorOK: true},
+		{name: "Page", qs: "page=3&page_size=50", wantPage: 3, wantPageSize: 50, wantSort: "-created_at", wantCursorOK: true},
+		{name: "Ascending with cursor", qs: "sort=created_at&cursor=abc", wantPageSize: DefaultPageSize, wantCursor: "abc", wantSort: "created_at", wantCursorOK: true},
+		{name: "Descending with cursor", qs: "sort=-created_at&cursor=abc", wantPageSize: DefaultPageSize, wantCursor: "abc", wantSort: "-created_at", wantCursorOK: true},
+		{name: "Ascending without cursor support", qs: "sort=email", wantPage: 1, wantPageSize: DefaultPageSize, wantSort: "email"},
+		{name: "Descending without cursor support", qs: "sort=-email", wantPage: 1, wantPageSize: DefaultPageSize, wantSort: "-email"},
+		{name: "Cursor with unsupported sort", qs: "sort=email&cursor=abc", wantPageSize: DefaultPageSize, wantCursor: "abc", wantSort: "email", wantFieldErrors: []string{"cursor"}},
+		{name: "Page with cursor", qs: "page=2&cursor=abc", wantPageSize: DefaultPageSize, wantCursor: "abc", wantSort: "-created_at", wantCursorOK: true, wantFieldErrors: []string{"page"}},
+		{name: "Unknown sort", qs: "sort=password", wantPage: 1, wantPageSize: DefaultPageSize, wantSort: "password", wantFieldErrors: []string{"sort"}},
+		{name: "Non-integer page", qs: "page=two", wantPageSize: DefaultPageSize, wantSort: "-created_at", wantCursorOK: true, wantFieldErrors: []string{"page"}},
+		{name: "Page out of range", qs: "page=0&page_size=101", wantPageSize: 101, wantSort: "-created_at", wantCursorOK: true, wantFieldErrors: []string{"page", "page_size"}},
+		{name: "Unknown filter", qs: "filter[password]=x", wantPage: 1, wantPageSize: DefaultPageSize, wantSort: "-created_at", wantCursorOK: true, wantFieldErrors: []string{"filter[password]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qs, err := url.ParseQuery(tt.qs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var v validator.Validator
+			p := Parse(&v, qs, testOptions)
+
+			if p.Page != tt.wantPage || p.PageSize != tt.wantPageSize || p.Cursor != tt.wantCursor || p.Sort != tt.wantSort || p.CursorSupported != tt.wantCursorOK {
+				t.Errorf("got page %d, page size %d, cursor %q, sort %q, cursor supported %t; want %d, %d, %q, %q, %t",
+					p.Page, p.PageSize, p.Cursor, p.Sort, p.CursorSupported,
+					tt.wantPage, tt.wantPageSize, tt.wantCursor, tt.wantSort, tt.wantCursorOK)
+			}
+
+			if len(v.FieldErrors) != len(tt.wantFieldErrors) {
+				t.Errorf("got field errors %v; want errors for %v", v.FieldErrors, tt.wantFieldErrors)
+			}
+
+			for _, key := range tt.wantFieldErrors {
+				if _, ok := v.FieldErrors[key]; !ok {
+					t.Errorf("got no error for %s; want one", key)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	qs, err := url.ParseQuery("filter[email]=alice&filter[name]=&filter=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v validator.Validator
+	p := Parse(&v, qs, Options{FilterSafelist: []string{"email", "name", "verified"}})
+
+	if v.HasErrors() {
+		t.Fatalf("got errors %v; want none", v.FieldErrors)
+	}
+
+	if got := p.Filter("email"); got == nil || *got != "alice" {
+		t.Errorf("got email filter %v; want alice", got)
+	}
+
+	if got := p.Filter("name"); got != nil {
+		t.Errorf("got empty name filter %q; want nil", *got)
+	}
+
+	if got := p.Filter("verified"); got != nil {
+		t.Errorf("got missing verified filter %q; want nil", *got)
+	}
+}
+
+func TestLimitAndOffset(t *testing.T) {
+	tests := []struct {
+		name       string
+		p          Params
+		wantOffset int32
+	}{
+		{name: "First page", p: Params{Page: 1, PageSize: 20}, wantOffset: 0},
+		{name: "Third page", p: Params{Page: 3, PageSize: 20}, wantOffset: 40},
+		{name: "Cursor", p: Params{PageSize: 20, Cursor: "abc"}, wantOffset: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p.Limit(); got != 20 {
+				t.Errorf("got limit %d; want 20", got)
+			}
+
+			if got := tt.p.Offset(); got != tt.wantOffset {
+				t.Errorf("got offset %d; want %d", got, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestNewMetadata(t *testing.T) {
+	tests := []struct {
+		name         string
+		p            Params
+		totalRecords int64
+		nextCursor   string
+		want         Metadata
+	}{
+		{
+			name:         "No records",
+			p:            Params{Page: 1, PageSize: 20},
+			totalRecords: 0,
+			want:         Metadata{CurrentPage: 1, PageSize: 20, FirstPage: 1, LastPage: 1},
+		},
+		{
+			name:         "Partial last page",
+			p:            Params{Page: 3, PageSize: 20},
+			totalRecords: 41,
+			want:         Metadata{CurrentPage: 3, PageSize: 20, FirstPage: 1, LastPage: 3, TotalRecords: 41},
+		},
+		{
+			name:         "Full last page",
+			p:            Params{Page: 2, PageSize: 20},
+			totalRecords: 40,
+			want:         Metadata{CurrentPage: 2, PageSize: 20, FirstPage: 1, LastPage: 2, TotalRecords: 40},
+		},
+		{
+			name:         "Page with a next cursor",
+			p:            Params{Page: 1, PageSize: 20, CursorSupported: true},
+			totalRecords: 41,
+			nextCursor:   "next",
+			want:         Metadata{CurrentPage: 1, PageSize: 20, FirstPage: 1, LastPage: 3, TotalRecords: 41, NextCursor: "next"},
+		},
+		{
+			name:         "Cursor",
+			p:            Params{PageSize: 20, Cursor: "abc", CursorSupported: true},
+			totalRecords: 25,
+			nextCursor:   "next",
+			want:         Metadata{PageSize: 20, TotalRecords: 25, NextCursor: "next"},
+		},
+		{
+			name:         "Last cursor page",
+			p:            Params{PageSize: 20, Cursor: "abc", CursorSupported: true},
+			totalRecords: 5,
+			want:         Metadata{PageSize: 20, TotalRecords: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewMetadata(tt.p, tt.totalRecords, tt.nextCursor); got != tt.want {
+				t.Errorf("got %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkHeader(t *testing.T) {
+	u, err := url.Parse("https://api.example.com/v1/admin/users?sort=email&page=2&cursor=old")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		m    Metadata
+		want string
+	}{
+		{
+			name: "Only page",
+			m:    Metadata{CurrentPage: 1, FirstPage: 1, LastPage: 1},
+			want: `<https://api.example.com/v1/admin/users?page=1&sort=email>; rel="first", ` +
+				`<https://api.example.com/v1/admin/users?page=1&sort=email>; rel="last"`,
+		},
+		{
+			name: "First page",
+			m:    Metadata{CurrentPage: 1, FirstPage: 1, LastPage: 3},
+			want: `<https://api.example.com/v1/admin/users?page=1&sort=email>; rel="first", ` +
+				`<https://api.example.com/v1/admin/users?page=2&sort=email>; rel="next", ` +
+				`<https://api.example.com/v1/admin/users?page=3&sort=email>; rel="last"`,
+		},
+		{
+			name: "Middle page",
+			m:    Metadata{CurrentPage: 2, FirstPage: 1, LastPage: 3},
+			want: `<https://api.example.com/v1/admin/users?page=1&sort=email>; rel="first", ` +
+				`<https://api.example.com/v1/admin/users?page=1&sort=email>; rel="prev", ` +
+				`<https://api.example.com/v1/admin/users?page=3&sort=email>; rel="next", ` +
+				`<https://api.example.com/v1/admin/users?page=3&sort=email>; rel="last"`,
+		},
+		{
+			name: "Last page",
+			m:    Metadata{CurrentPage: 3, FirstPage: 1, LastPage: 3},
+			want: `<https://api.example.com/v1/admin/users?page=1&sort=email>; rel="first", ` +
+				`<https://api.example.com/v1/admin/users?page=2&sort=email>; rel="prev", ` +
+				`<https://api.example.com/v1/admin/users?page=3&sort=email>; rel="last"`,
+		},
+		{
+			name: "Past the last page",
+			m:    Metadata{CurrentPage: 7, FirstPage: 1, LastPage: 3},
+			want: `<https://api.example.com/v1/admin/users?page=1&sort=email>; rel="first", ` +
+				`<https://api.example.com/v1/admin/users?page=3&sort=email>; rel="prev", ` +
+				`<https://api.example.com/v1/admin/users?page=3&sort=email>; rel="last"`,
+		},
+		{
+			name: "Cursor page",
+			m:    Metadata{NextCursor: "next+/"},
+			want: `<https://api.example.com/v1/admin/users?sort=email>; rel="first", ` +
+				`<https://api.example.com/v1/admin/users?cursor=next%2B%2F&sort=email>; rel="next"`,
+		},
+		{
+			name: "Last cursor page",
+			m:    Metadata{},
+			want: `<https://api.example.com/v1/admin/users?sort=email>; rel="first"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LinkHeader(u, tt.m); got != tt.want {
+				t.Errorf("got %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package ratelimit
+
+import (
+	"context"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/database"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table, so that every
+// instance of the application shares the same limits.
+type PostgresStore struct {
+	db *database.Queries
+}
+
+func NewPostgresStore(db *database.Queries) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	row, err := s.db.TakeRateLimitToken(ctx, database.TakeRateLimitTokenParams{
+		Key:           key,
+		Burst:         float64(limit.Requests),
+		PeriodSeconds: limit.Period.Seconds(),
+		RefillRate:    limit.refillRate(),
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	return newResult(row.Allowed, row.Tokens, limit), nil
+}
+
+// DeleteExpired removes buckets that have had time to refill completely.
+func (s *PostgresStore) DeleteExpired(ctx context.Context) (int64, error) {
+	return s.db.DeleteExpiredRateLimitBuckets(ctx)
+}
@@ -0,0 +1,94 @@
+// Package ratelimit implements token bucket rate limiting. Each key, such as
+// "login:ip:203.0.113.7", has a bucket holding up to Limit.Requests tokens
+// that refills at Limit.Requests per Limit.Period. Every request takes a
+// token and is refused when the bucket is empty.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests requests per Period, all of which may be made in a
+// burst. The zero Limit allows everything.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit parses a limit written as "<requests>/<period>", such as
+// "10/1m". An empty string parses to the zero Limit, and is the only way to
+// disable a limit: a request count of 0 is rejected, so that "0/1m" can't be
+// mistaken for a limit that blocks everything.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" {
+		return Limit{}, nil
+	}
+
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("ratelimit: invalid limit %q", s)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: invalid request count in %q", s)
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: invalid period in %q", s)
+	}
+
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// Unlimited reports whether l is the zero Limit.
+func (l Limit) Unlimited() bool {
+	return l.Requests == 0
+}
+
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return ""
+	}
+
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// refillRate returns the number of tokens added to a bucket per second.
+func (l Limit) refillRate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Result describes the outcome of taking a token. RetryAfter is only set
+// when the request isn't allowed.
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+func newResult(allowed bool, tokens float64, limit Limit) Result {
+	res := Result{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+	}
+
+	if !allowed {
+		seconds := (1 - tokens) / limit.refillRate()
+		res.RetryAfter = time.Duration(seconds * float64(time.Second))
+	}
+
+	return res
+}
+
+// Store holds token buckets. Take must be safe for concurrent use and must
+// refill and take from the bucket for key atomically.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -0,0 +1,190 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    Limit
+		wantErr bool
+	}{
+		{name: "Empty", s: "", want: Limit{}},
+		{name: "Valid", s: "10/1m", want: Limit{Requests: 10, Period: time.Minute}},
+		{name: "Compound period", s: "5/1h30m", want: Limit{Requests: 5, Period: 90 * time.Minute}},
+		{name: "Zero requests", s: "0/1m", wantErr: true},
+		{name: "Negative requests", s: "-1/1m", wantErr: true},
+		{name: "Non-numeric requests", s: "ten/1m", wantErr: true},
+		{name: "Missing requests", s: "/1m", wantErr: true},
+		{name: "Missing period", s: "10/", wantErr: true},
+		{name: "Missing slash", s: "10", wantErr: true},
+		{name: "Period without unit", s: "10/60", wantErr: true},
+		{name: "Zero period", s: "10/0s", wantErr: true},
+		{name: "Negative period", s: "10/-1m", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLimit(tt.s)
+
+			switch {
+			case tt.wantErr && err == nil:
+				t.Errorf("got %+v; want an error", got)
+			case !tt.wantErr && err != nil:
+				t.Errorf("got error %v; want nil", err)
+			case got != tt.want:
+				t.Errorf("got %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitString(t *testing.T) {
+	for _, s := range []string{"", "10/1m0s", "5/1h0m0s"} {
+		limit, err := ParseLimit(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := limit.String(); got != s {
+			t.Errorf("got %q; want %q", got, s)
+		}
+	}
+}
+
+func TestNewResult(t *testing.T) {
+	limit := Limit{Requests: 10, Period: time.Minute}
+
+	tests := []struct {
+		name           string
+		allowed        bool
+		tokens         float64
+		wantRemaining  int
+		wantRetryAfter time.Duration
+	}{
+		{name: "Allowed", allowed: true, tokens: 4.7, wantRemaining: 4},
+		{name: "Allowed with none left", allowed: true, tokens: 0.2, wantRemaining: 0},
+		{name: "Refused with an empty bucket", tokens: 0, wantRetryAfter: 6 * time.Second},
+		{name: "Refused with part of a token", tokens: 0.5, wantRetryAfter: 3 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := newResult(tt.allowed, tt.tokens, limit)
+
+			want := Result{Allowed: tt.allowed, Remaining: tt.wantRemaining, RetryAfter: tt.wantRetryAfter}
+			if res != want {
+				t.Errorf("got %+v; want %+v", res, want)
+			}
+		})
+	}
+}
+
+// newTestMemoryStore returns a MemoryStore with a clock that only moves when
+// the returned function is called.
+func newTestMemoryStore() (*MemoryStore, func(time.Duration)) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	s.lastSweep = now
+
+	return s, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestMemoryStore(t *testing.T) {
+	limit := Limit{Requests: 3, Period: 30 * time.Second}
+
+	// Each step waits, then takes a token.
+	steps := []struct {
+		wait           time.Duration
+		wantAllowed    bool
+		wantRemaining  int
+		wantRetryAfter time.Duration
+	}{
+		{wantAllowed: true, wantRemaining: 2},
+		{wantAllowed: true, wantRemaining: 1},
+		{wantAllowed: true, wantRemaining: 0},
+		{wantAllowed: false, wantRetryAfter: 10 * time.Second},
+		{wait: 5 * time.Second, wantAllowed: false, wantRetryAfter: 5 * time.Second},
+		{wait: 5 * time.Second, wantAllowed: true, wantRemaining: 0},
+		{wait: time.Hour, wantAllowed: true, wantRemaining: 2},
+	}
+
+	s, advance := newTestMemoryStore()
+
+	for i, step := range steps {
+		advance(step.wait)
+
+		res, err := s.Take(context.Background(), "key", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := Result{Allowed: step.wantAllowed, Remaining: step.wantRemaining, RetryAfter: step.wantRetryAfter}
+		if res != want {
+			t.Errorf("step %d: got %+v; want %+v", i, res, want)
+		}
+	}
+}
+
+func TestMemoryStoreKeysAreSeparate(t *testing.T) {
+	limit := Limit{Requests: 1, Period: time.Minute}
+	s, _ := newTestMemoryStore()
+
+	for _, key := range []string{"a", "b"} {
+		res, err := s.Take(context.Background(), key, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !res.Allowed {
+			t.Errorf("got the first request for %q refused; want it allowed", key)
+		}
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	s, advance := newTestMemoryStore()
+
+	_, err := s.Take(context.Background(), "short", Limit{Requests: 1, Period: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.Take(context.Background(), "long", Limit{Requests: 1, Period: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Before the sweep interval, nothing is dropped.
+	advance(sweepInterval / 2)
+
+	_, err = s.Take(context.Background(), "other", Limit{Requests: 1, Period: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.buckets) != 3 {
+		t.Fatalf("got %d buckets before the sweep interval; want 3", len(s.buckets))
+	}
+
+	advance(sweepInterval)
+
+	_, err = s.Take(context.Background(), "other", Limit{Requests: 1, Period: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.buckets["short"]; ok {
+		t.Error("got the refilled bucket kept; want it swept")
+	}
+
+	if _, ok := s.buckets["long"]; !ok {
+		t.Error("got the bucket that is still refilling swept; want it kept")
+	}
+}
@@ -10,6 +10,10 @@ func JSON(w http.ResponseWriter, status int, data any) error {
 }
 
 func JSONWithHeaders(w http.ResponseWriter, status int, data any, headers http.Header) error {
+	return writeJSON(w, status, "application/json", data, headers)
+}
+
+func writeJSON(w http.ResponseWriter, status int, contentType string, data any, headers http.Header) error {
 	js, err := json.MarshalIndent(data, "", "\t")
 	if err != nil {
 		return err
@@ -21,7 +25,7 @@ func JSONWithHeaders(w http.ResponseWriter, status int, data any, headers http.H
 		w.Header()[key] = value
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(status)
 	w.Write(js)
 
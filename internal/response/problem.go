@@ -0,0 +1,38 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/validator"
+)
+
+// Problem is an RFC 9457 problem details object. Code, RequestID and the
+// validation error members are extension members.
+type Problem struct {
+	Type              string                     `json:"type"`
+	Title             string                     `json:"title"`
+	Status            int                        `json:"status"`
+	Detail            string                     `json:"detail,omitempty"`
+	Instance          string                     `json:"instance,omitempty"`
+	Code              string                     `json:"code,omitempty"`
+	RequestID         string                     `json:"request_id,omitempty"`
+	Errors            []string                   `json:"errors,omitempty"`
+	FieldErrors       map[string]string          `json:"field_errors,omitempty"`
+	ErrorDetails      []validator.Error          `json:"error_details,omitempty"`
+	FieldErrorDetails map[string]validator.Error `json:"field_error_details,omitempty"`
+}
+
+// NewProblem returns a Problem of the generic "about:blank" type, titled
+// with the standard text for status.
+func NewProblem(status int, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func ProblemJSON(w http.ResponseWriter, problem Problem, headers http.Header) error {
+	return writeJSON(w, problem.Status, "application/problem+json", problem, headers)
+}
@@ -0,0 +1,58 @@
+package revocation
+
+import (
+	"sync"
+	"time"
+)
+
+// List is an in-memory set of revoked token IDs. Each entry is kept until the
+// token it refers to would have expired anyway.
+type List struct {
+	mu      sync.RWMutex
+	entries map[string]time.Time
+}
+
+func NewList() *List {
+	return &List{
+		entries: make(map[string]time.Time),
+	}
+}
+
+func (l *List) Add(id string, expiresAt time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries[id] = expiresAt
+}
+
+func (l *List) Contains(id string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	expiresAt, exists := l.entries[id]
+	if !exists {
+		return false
+	}
+
+	return time.Now().Before(expiresAt)
+}
+
+// Merge adds the given entries to the list and drops any entries that have
+// expired. Revocations are never undone, so entries already in the list are
+// kept even when they are missing from the given set.
+func (l *List) Merge(entries map[string]time.Time) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for id, expiresAt := range entries {
+		l.entries[id] = expiresAt
+	}
+
+	for id, expiresAt := range l.entries {
+		if !now.Before(expiresAt) {
+			delete(l.entries, id)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package smtp
+
+import (
+	"time"
+
+	"github.com/jcarloasilo/golang-rest-template/internal/mailer"
+
+	"github.com/wneessen/go-mail"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Transport delivers messages through an SMTP server.
+type Transport struct {
+	client *mail.Client
+	from   string
+}
+
+func NewTransport(host string, port int, username, password, from string) (*Transport, error) {
+	client, err := mail.NewClient(host, mail.WithTimeout(defaultTimeout), mail.WithSMTPAuth(mail.SMTPAuthLogin), mail.WithPort(port), mail.WithUsername(username), mail.WithPassword(password))
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &Transport{
+		client: client,
+		from:   from,
+	}
+
+	return transport, nil
+}
+
+// Deliver sends message to recipient, trying up to three times.
+func (t *Transport) Deliver(recipient string, message mailer.Message) error {
+	msg, err := mailer.NewMsg(t.from, recipient, message)
+	if err != nil {
+		return err
+	}
+
+	for i := 1; i <= 3; i++ {
+		err = t.client.DialAndSend(msg)
+
+		if nil == err {
+			return nil
+		}
+
+		if i != 3 {
+			time.Sleep(2 * time.Second)
+		}
+	}
+
+	return err
+}
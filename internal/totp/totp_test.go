@@ -0,0 +1,152 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 seed from RFC 6238 Appendix B, "12345678901234567890"
+// in ASCII, base32-encoded.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// The RFC lists 8-digit codes; with 6 digits each code is the last six
+	// digits of the RFC's.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(time.Unix(tt.unix, 0).UTC().Format(time.RFC3339), func(t *testing.T) {
+			got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodeLowercaseSecret(t *testing.T) {
+	want, err := Code(rfcSecret, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Code(strings.ToLower(rfcSecret), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != want {
+		t.Errorf("got %s for a lowercase secret; want %s", got, want)
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	_, err := Code("not base32!", 1)
+	if err == nil {
+		t.Error("got nil error; want an error for an invalid secret")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	codeAt := func(step int64) string {
+		code, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name         string
+		code         string
+		skew         int64
+		lastUsedStep int64
+		wantStep     int64
+		wantValid    bool
+	}{
+		{name: "Current step", code: codeAt(current), skew: 1, wantStep: current, wantValid: true},
+		{name: "Previous step within skew", code: codeAt(current - 1), skew: 1, wantStep: current - 1, wantValid: true},
+		{name: "Next step within skew", code: codeAt(current + 1), skew: 1, wantStep: current + 1, wantValid: true},
+		{name: "Previous step without skew", code: codeAt(current - 1), skew: 0},
+		{name: "Outside skew", code: codeAt(current - 2), skew: 1},
+		{name: "Wrong code", code: "000000", skew: 1},
+		{name: "Empty code", code: "", skew: 1},
+		{name: "Replayed step", code: codeAt(current), skew: 1, lastUsedStep: current},
+		{name: "Step before the last used one", code: codeAt(current - 1), skew: 1, lastUsedStep: current},
+		{name: "Step after the last used one", code: codeAt(current), skew: 1, lastUsedStep: current - 1, wantStep: current, wantValid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, valid, err := Validate(rfcSecret, tt.code, now, tt.skew, tt.lastUsedStep)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if valid != tt.wantValid || step != tt.wantStep {
+				t.Errorf("got step %d, valid %t; want step %d, valid %t", step, valid, tt.wantStep, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(key) != secretSize {
+		t.Errorf("got a %d-byte secret; want %d bytes", len(key), secretSize)
+	}
+}
+
+func TestURI(t *testing.T) {
+	u, err := url.Parse(URI("Example", "alice@example.com", rfcSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Example:alice@example.com" {
+		t.Errorf("got %s; want otpauth://totp/Example:alice@example.com", u)
+	}
+
+	want := map[string]string{
+		"secret":    rfcSecret,
+		"issuer":    "Example",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	}
+
+	for key, value := range want {
+		if got := u.Query().Get(key); got != value {
+			t.Errorf("got %s=%q; want %q", key, got, value)
+		}
+	}
+}
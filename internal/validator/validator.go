@@ -1,40 +1,108 @@
 package validator
 
+// Error describes a single validation failure. Code is a stable identifier
+// such as "password.too_common" that clients can branch on or translate,
+// and Params holds the values it refers to, such as {"min": 8}.
+type Error struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Params  map[string]any `json:"params,omitempty"`
+}
+
+// Validator collects validation failures. Errors and FieldErrors hold just
+// the messages, while ErrorDetails and FieldErrorDetails hold the same
+// failures with their codes and parameters.
 type Validator struct {
-	Errors      []string          `json:"errors,omitempty"`
-	FieldErrors map[string]string `json:"field_errors,omitempty"`
+	Errors            []string          `json:"errors,omitempty"`
+	FieldErrors       map[string]string `json:"field_errors,omitempty"`
+	ErrorDetails      []Error           `json:"error_details,omitempty"`
+	FieldErrorDetails map[string]Error  `json:"field_error_details,omitempty"`
 }
 
 func (v Validator) HasErrors() bool {
 	return len(v.Errors) != 0 || len(v.FieldErrors) != 0
 }
 
-func (v *Validator) AddError(message string) {
+// AddError records an error that isn't tied to a field. params are
+// alternating key and value pairs, as in log/slog.
+func (v *Validator) AddError(code, message string, params ...any) {
 	if v.Errors == nil {
 		v.Errors = []string{}
 	}
 
 	v.Errors = append(v.Errors, message)
+	v.ErrorDetails = append(v.ErrorDetails, newError(code, message, params))
 }
 
-func (v *Validator) AddFieldError(key, message string) {
+// AddFieldError records an error for key, unless key already has one.
+// params are alternating key and value pairs, as in log/slog.
+func (v *Validator) AddFieldError(key, code, message string, params ...any) {
 	if v.FieldErrors == nil {
 		v.FieldErrors = map[string]string{}
+		v.FieldErrorDetails = map[string]Error{}
 	}
 
 	if _, exists := v.FieldErrors[key]; !exists {
 		v.FieldErrors[key] = message
+		v.FieldErrorDetails[key] = newError(code, message, params)
 	}
 }
 
-func (v *Validator) Check(ok bool, message string) {
+func (v *Validator) Check(ok bool, code, message string, params ...any) {
 	if !ok {
-		v.AddError(message)
+		v.AddError(code, message, params...)
 	}
 }
 
-func (v *Validator) CheckField(ok bool, key, message string) {
+func (v *Validator) CheckField(ok bool, key, code, message string, params ...any) {
 	if !ok {
-		v.AddFieldError(key, message)
+		v.AddFieldError(key, code, message, params...)
+	}
+}
+
+// Translate returns a copy of v with every message replaced by the result
+// of calling translate with its error. Codes and parameters are unchanged.
+func (v Validator) Translate(translate func(e Error) string) Validator {
+	var t Validator
+
+	for _, e := range v.ErrorDetails {
+		e.Message = translate(e)
+		t.Errors = append(t.Errors, e.Message)
+		t.ErrorDetails = append(t.ErrorDetails, e)
+	}
+
+	for key, e := range v.FieldErrorDetails {
+		if t.FieldErrors == nil {
+			t.FieldErrors = map[string]string{}
+			t.FieldErrorDetails = map[string]Error{}
+		}
+
+		e.Message = translate(e)
+		t.FieldErrors[key] = e.Message
+		t.FieldErrorDetails[key] = e
 	}
+
+	return t
+}
+
+// Params turns alternating key and value pairs into a map, skipping any
+// pair whose key isn't a string.
+func Params(params ...any) map[string]any {
+	if len(params) == 0 {
+		return nil
+	}
+
+	m := make(map[string]any, len(params)/2)
+	for i := 0; i+1 < len(params); i += 2 {
+		key, ok := params[i].(string)
+		if ok {
+			m[key] = params[i+1]
+		}
+	}
+
+	return m
+}
+
+func newError(code, message string, params []any) Error {
+	return Error{Code: code, Message: message, Params: Params(params...)}
 }
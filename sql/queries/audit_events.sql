@@ -0,0 +1,8 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (user_id, action, ip_address)
+VALUES ($1, $2, $3);
+
+-- name: GetUserAuditEvents :many
+SELECT * FROM audit_events
+WHERE user_id = $1
+ORDER BY created_at;
@@ -0,0 +1,65 @@
+-- name: InsertJob :execrows
+-- Inserts nothing if a job with the same unique key is already waiting to
+-- run. Jobs without a unique key never conflict, as NULLs are distinct.
+INSERT INTO jobs (kind, payload, unique_key, max_attempts, run_at)
+VALUES (sqlc.arg(kind), sqlc.arg(payload), sqlc.narg(unique_key), sqlc.arg(max_attempts), sqlc.arg(run_at))
+ON CONFLICT (unique_key) WHERE failed_at IS NULL DO NOTHING;
+
+-- name: ClaimJob :one
+-- Takes the next job of one of the given kinds that is due and holds it until
+-- lease_until, so that other workers skip it while it runs. If the worker
+-- dies, the job runs again once the lease runs out.
+UPDATE jobs
+SET attempts = attempts + 1, run_at = sqlc.arg(lease_until)
+WHERE id = (
+    SELECT id FROM jobs
+    WHERE failed_at IS NULL AND run_at <= CURRENT_TIMESTAMP AND kind = ANY(sqlc.arg(kinds)::TEXT[])
+    ORDER BY run_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: DeleteJob :exec
+DELETE FROM jobs
+WHERE id = sqlc.arg(id)::UUID;
+
+-- name: RetryJob :exec
+UPDATE jobs
+SET last_error = sqlc.arg(last_error), run_at = sqlc.arg(run_at)
+WHERE id = sqlc.arg(id)::UUID;
+
+-- name: FailJob :exec
+UPDATE jobs
+SET last_error = sqlc.arg(last_error), failed_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id)::UUID;
+
+-- name: GetFailedJobs :many
+SELECT count(*) OVER() AS total_count, id, kind, payload, unique_key, attempts, max_attempts, last_error, run_at, failed_at, created_at FROM jobs
+WHERE failed_at IS NOT NULL
+ORDER BY failed_at DESC, id
+LIMIT sqlc.arg(page_limit)::INT OFFSET sqlc.arg(page_offset)::INT;
+
+-- name: GetJobStats :many
+-- Counts the jobs of each kind that are waiting for their first attempt,
+-- waiting to be retried after a failure, and failed for good.
+SELECT kind,
+    count(*) FILTER (WHERE failed_at IS NULL AND last_error IS NULL) AS pending,
+    count(*) FILTER (WHERE failed_at IS NULL AND last_error IS NOT NULL) AS retrying,
+    count(*) FILTER (WHERE failed_at IS NOT NULL) AS failed
+FROM jobs
+GROUP BY kind
+ORDER BY kind;
+
+-- name: RetryFailedJob :execrows
+-- Gives a failed job a fresh set of attempts, unless another job with the
+-- same unique key is already waiting.
+-- The condition must match idx_jobs_unique_key, which the jobs package tests
+-- check.
+UPDATE jobs
+SET attempts = 0, failed_at = NULL, run_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id)::UUID AND failed_at IS NOT NULL
+    AND NOT EXISTS (
+        SELECT 1 FROM jobs AS waiting
+        WHERE waiting.unique_key = jobs.unique_key AND waiting.failed_at IS NULL
+    );
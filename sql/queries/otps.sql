@@ -1,6 +1,12 @@
--- name: CreateOTP :exec
-INSERT INTO otps (code, type, user_id, expires_at, created_at)
-VALUES ($1, $2, sqlc.arg(user_id)::UUID, $3, $4);
+-- name: ReplaceOTP :exec
+-- Deletes the user's OTPs of the given type and inserts the new one in a
+-- single statement.
+WITH deleted AS (
+    DELETE FROM otps
+    WHERE user_id = sqlc.arg(user_id)::UUID AND type = sqlc.arg(type)
+)
+INSERT INTO otps (code_hash, type, user_id, max_attempts, expires_at, created_at)
+VALUES (sqlc.arg(code_hash), sqlc.arg(type), sqlc.arg(user_id)::UUID, sqlc.arg(max_attempts), sqlc.arg(expires_at), sqlc.arg(created_at));
 
 -- name: InvalidateExistingOTP :exec
 DELETE FROM otps
@@ -12,10 +18,39 @@ WHERE user_id = sqlc.arg(user_id)::UUID AND type = $1
 ORDER BY created_at DESC
 LIMIT 1;
 
--- name: IncrementOTPAttempts :exec
-UPDATE otps
-SET attempts = attempts + 1
-WHERE id = sqlc.arg(id)::UUID;
+-- name: ConsumeOTP :one
+-- Checks code_hash against the user's latest OTP of the given type. If the
+-- OTP is still usable, it is deleted when the hash matches and has its
+-- attempts incremented otherwise. The row is locked, so of two concurrent
+-- calls with the right code only one consumes the OTP. The hashes are keyed
+-- HMACs, so comparing them with = leaks nothing useful through timing.
+WITH latest AS (
+    SELECT id, code_hash,
+        expires_at <= CURRENT_TIMESTAMP AS expired,
+        attempts >= max_attempts AS exhausted
+    FROM otps
+    WHERE user_id = sqlc.arg(user_id)::UUID AND type = sqlc.arg(type)
+    ORDER BY created_at DESC
+    LIMIT 1
+    FOR UPDATE
+), consumed AS (
+    DELETE FROM otps
+    WHERE id IN (
+        SELECT id FROM latest
+        WHERE NOT expired AND NOT exhausted AND code_hash = sqlc.arg(code_hash)
+    )
+    RETURNING id
+), attempted AS (
+    UPDATE otps
+    SET attempts = attempts + 1
+    WHERE id IN (
+        SELECT id FROM latest
+        WHERE NOT expired AND NOT exhausted AND code_hash IS DISTINCT FROM sqlc.arg(code_hash)
+    )
+    RETURNING id
+)
+SELECT EXISTS (SELECT 1 FROM consumed) AS consumed, latest.expired, latest.exhausted
+FROM latest;
 
 -- name: ExpireOTP :exec
 UPDATE otps
@@ -25,3 +60,17 @@ WHERE id = sqlc.arg(id)::UUID;
 -- name: DeleteOTP :exec
 DELETE FROM otps
 WHERE id = sqlc.arg(id)::UUID;
+
+-- name: GetUserOTPs :many
+SELECT id, type, expires_at, created_at, attempts FROM otps
+WHERE user_id = sqlc.arg(user_id)::UUID
+ORDER BY created_at;
+
+-- name: GetPlaintextOTPs :many
+SELECT id, code FROM otps
+WHERE code IS NOT NULL;
+
+-- name: SetOTPCodeHash :exec
+UPDATE otps
+SET code_hash = sqlc.arg(code_hash), code = NULL
+WHERE id = sqlc.arg(id)::UUID;
@@ -0,0 +1,23 @@
+-- name: TakeRateLimitToken :one
+-- Refills the bucket for the time since it was last used and takes a token
+-- if there is one, in a single statement so that concurrent requests can't
+-- both take the last token.
+INSERT INTO rate_limit_buckets (key, tokens, allowed, updated_at, expires_at)
+VALUES (
+    sqlc.arg(key),
+    sqlc.arg(burst)::FLOAT8 - 1,
+    TRUE,
+    CURRENT_TIMESTAMP,
+    CURRENT_TIMESTAMP + sqlc.arg(period_seconds)::FLOAT8 * INTERVAL '1 second'
+)
+ON CONFLICT (key) DO UPDATE
+SET tokens = LEAST(sqlc.arg(burst)::FLOAT8, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - rate_limit_buckets.updated_at)::FLOAT8 * sqlc.arg(refill_rate)::FLOAT8)
+        - CASE WHEN LEAST(sqlc.arg(burst)::FLOAT8, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - rate_limit_buckets.updated_at)::FLOAT8 * sqlc.arg(refill_rate)::FLOAT8) >= 1 THEN 1 ELSE 0 END,
+    allowed = LEAST(sqlc.arg(burst)::FLOAT8, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - rate_limit_buckets.updated_at)::FLOAT8 * sqlc.arg(refill_rate)::FLOAT8) >= 1,
+    updated_at = CURRENT_TIMESTAMP,
+    expires_at = CURRENT_TIMESTAMP + sqlc.arg(period_seconds)::FLOAT8 * INTERVAL '1 second'
+RETURNING tokens, allowed;
+
+-- name: DeleteExpiredRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE expires_at <= CURRENT_TIMESTAMP;
@@ -0,0 +1,12 @@
+-- name: RevokeToken :exec
+INSERT INTO revoked_tokens (jti, user_id, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: GetActiveRevokedTokens :many
+SELECT jti, expires_at FROM revoked_tokens
+WHERE expires_at > CURRENT_TIMESTAMP;
+
+-- name: DeleteExpiredRevokedTokens :exec
+DELETE FROM revoked_tokens
+WHERE expires_at <= CURRENT_TIMESTAMP;
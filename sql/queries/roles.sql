@@ -0,0 +1,36 @@
+-- name: GetRoles :many
+SELECT * FROM roles
+ORDER BY name;
+
+-- name: GetRoleByName :one
+SELECT * FROM roles
+WHERE name = $1;
+
+-- name: GetRolePermissionNames :many
+SELECT roles.name AS role_name, permissions.name AS permission_name
+FROM role_permissions
+JOIN roles ON roles.id = role_permissions.role_id
+JOIN permissions ON permissions.id = role_permissions.permission_id
+ORDER BY roles.name, permissions.name;
+
+-- name: GetUserRoleNames :many
+SELECT roles.name FROM roles
+JOIN user_roles ON user_roles.role_id = roles.id
+WHERE user_roles.user_id = $1
+ORDER BY roles.name;
+
+-- name: GetUserPermissionNames :many
+SELECT DISTINCT permissions.name FROM permissions
+JOIN role_permissions ON role_permissions.permission_id = permissions.id
+JOIN user_roles ON user_roles.role_id = role_permissions.role_id
+WHERE user_roles.user_id = $1
+ORDER BY permissions.name;
+
+-- name: AssignUserRole :exec
+INSERT INTO user_roles (user_id, role_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveUserRole :exec
+DELETE FROM user_roles
+WHERE user_id = $1 AND role_id = $2;
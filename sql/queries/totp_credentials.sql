@@ -0,0 +1,23 @@
+-- name: UpsertTOTPCredential :exec
+INSERT INTO totp_credentials (user_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret, last_used_step = 0, confirmed_at = NULL, created_at = CURRENT_TIMESTAMP;
+
+-- name: GetTOTPCredential :one
+SELECT * FROM totp_credentials
+WHERE user_id = $1;
+
+-- name: ConfirmTOTPCredential :exec
+UPDATE totp_credentials
+SET confirmed_at = CURRENT_TIMESTAMP, last_used_step = sqlc.arg(last_used_step)
+WHERE user_id = sqlc.arg(user_id)::UUID;
+
+-- name: UseTOTPStep :execrows
+UPDATE totp_credentials
+SET last_used_step = sqlc.arg(step)
+WHERE user_id = sqlc.arg(user_id)::UUID AND last_used_step < sqlc.arg(step);
+
+-- name: DeleteTOTPCredential :exec
+DELETE FROM totp_credentials
+WHERE user_id = $1;
@@ -1,5 +1,21 @@
 -- name: GetUsers :many
-SELECT * FROM users;
+SELECT *, count(*) OVER() AS total_count FROM users
+WHERE (sqlc.narg(email)::TEXT IS NULL OR email ILIKE '%' || sqlc.narg(email) || '%')
+  AND (sqlc.narg(verified)::BOOLEAN IS NULL OR (verified_at IS NOT NULL) = sqlc.narg(verified))
+  AND (sqlc.narg(disabled)::BOOLEAN IS NULL OR (disabled_at IS NOT NULL) = sqlc.narg(disabled))
+  AND (sqlc.narg(cursor_created_at)::TIMESTAMPTZ IS NULL
+    OR (sqlc.arg(sort)::TEXT = 'created_at' AND (created_at, id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::UUID))
+    OR (sqlc.arg(sort) = '-created_at' AND (created_at, id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id))))
+ORDER BY
+  CASE WHEN sqlc.arg(sort) = 'created_at' THEN created_at END ASC,
+  CASE WHEN sqlc.arg(sort) = '-created_at' THEN created_at END DESC,
+  CASE WHEN sqlc.arg(sort) = 'email' THEN email END ASC,
+  CASE WHEN sqlc.arg(sort) = '-email' THEN email END DESC,
+  CASE WHEN sqlc.arg(sort) = 'name' THEN name END ASC,
+  CASE WHEN sqlc.arg(sort) = '-name' THEN name END DESC,
+  CASE WHEN sqlc.arg(sort) LIKE '-%' THEN id END DESC,
+  id ASC
+LIMIT sqlc.arg(page_limit)::INT OFFSET sqlc.arg(page_offset)::INT;
 
 -- name: GetUserByEmail :one
 SELECT * FROM users WHERE email = $1;
@@ -12,5 +28,90 @@ INSERT INTO users (email, name, hashed_password) VALUES ($1, $2, $3) RETURNING *
 
 -- name: VerifyUser :exec
 UPDATE users
-SET verified_at = sqlc.arg(verified_at)::TIMESTAMPTZ
-WHERE id = sqlc.arg(user_id)::UUID;
\ No newline at end of file
+SET verified_at = sqlc.arg(verified_at)::TIMESTAMPTZ, updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(user_id)::UUID;
+
+-- name: RevokeUserTokens :exec
+UPDATE users
+SET tokens_revoked_at = sqlc.arg(tokens_revoked_at)::TIMESTAMPTZ
+WHERE id = sqlc.arg(user_id)::UUID;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = sqlc.arg(hashed_password), updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(user_id)::UUID;
+
+-- name: SetUserTwoFactorEnabled :exec
+UPDATE users
+SET two_factor_enabled = sqlc.arg(two_factor_enabled), updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(user_id)::UUID;
+
+-- name: UpdateUser :one
+UPDATE users
+SET name = sqlc.arg(name),
+    email = sqlc.arg(email),
+    verified_at = CASE WHEN email = sqlc.arg(email) THEN verified_at ELSE NULL END,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(user_id)::UUID
+RETURNING *;
+
+-- name: SetUserDisabled :exec
+UPDATE users
+SET disabled_at = sqlc.narg(disabled_at), updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(user_id)::UUID;
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1;
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET name = COALESCE(sqlc.narg(name), name),
+    locale = COALESCE(sqlc.narg(locale), locale),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(user_id)::UUID
+RETURNING *;
+
+-- name: SetUserPendingEmail :exec
+UPDATE users
+SET pending_email = sqlc.narg(pending_email), updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(user_id)::UUID;
+
+-- name: ConfirmUserEmailChange :one
+UPDATE users
+SET email = pending_email,
+    pending_email = NULL,
+    verified_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(user_id)::UUID AND pending_email IS NOT NULL
+RETURNING *;
+
+-- name: SetUserDeleted :exec
+UPDATE users
+SET deleted_at = sqlc.arg(deleted_at)::TIMESTAMPTZ, updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(user_id)::UUID;
+
+-- name: RestoreDeletedUser :execrows
+UPDATE users
+SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NOT NULL;
+
+-- name: PurgeDeletedUsers :execrows
+DELETE FROM users
+WHERE deleted_at <= sqlc.arg(deleted_before)::TIMESTAMPTZ;
+
+-- name: RecordFailedLogin :one
+UPDATE users
+SET failed_login_attempts = failed_login_attempts + 1
+WHERE id = $1
+RETURNING failed_login_attempts;
+
+-- name: LockUser :exec
+UPDATE users
+SET locked_until = sqlc.arg(locked_until)::TIMESTAMPTZ
+WHERE id = sqlc.arg(user_id)::UUID;
+
+-- name: ResetFailedLogins :exec
+UPDATE users
+SET failed_login_attempts = 0, locked_until = NULL
+WHERE id = $1;
@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN tokens_revoked_at TIMESTAMPTZ;
+
+CREATE TABLE revoked_tokens(
+    jti TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_revoked_tokens_expires_at;
+DROP TABLE revoked_tokens;
+ALTER TABLE users DROP COLUMN tokens_revoked_at;
@@ -0,0 +1,10 @@
+-- +goose NO TRANSACTION
+
+-- +goose Up
+ALTER TYPE otp_type ADD VALUE IF NOT EXISTS 'email_change';
+ALTER TABLE users ADD COLUMN pending_email TEXT;
+
+-- +goose Down
+-- Postgres cannot drop a value from an enum type, so 'email_change' is left in place.
+DELETE FROM otps WHERE type = 'email_change';
+ALTER TABLE users DROP COLUMN pending_email;
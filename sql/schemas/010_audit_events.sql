@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE audit_events(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    action TEXT NOT NULL,
+    ip_address TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_events_user_id ON audit_events(user_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_audit_events_user_id;
+DROP TABLE audit_events;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN locale TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN locale;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE rate_limit_buckets(
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_rate_limit_buckets_expires_at ON rate_limit_buckets(expires_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_rate_limit_buckets_expires_at;
+DROP TABLE rate_limit_buckets;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Codes are now stored as HMAC-SHA256 hashes. The plaintext column is kept,
+-- nullable, only until the application has hashed the codes issued before
+-- this migration; it is cleared as each one is hashed.
+ALTER TABLE otps ADD COLUMN code_hash BYTEA;
+ALTER TABLE otps ALTER COLUMN code DROP NOT NULL;
+DROP INDEX IF EXISTS idx_otps_code;
+
+-- +goose Down
+DELETE FROM otps WHERE code IS NULL;
+ALTER TABLE otps ALTER COLUMN code SET NOT NULL;
+CREATE INDEX idx_otps_code ON otps(code);
+ALTER TABLE otps DROP COLUMN code_hash;
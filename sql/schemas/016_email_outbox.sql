@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE email_outbox(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    recipient TEXT NOT NULL,
+    locale TEXT NOT NULL DEFAULT '',
+    template TEXT NOT NULL,
+    data JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    dead_lettered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_email_outbox_next_attempt_at ON email_outbox(next_attempt_at) WHERE dead_lettered_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_email_outbox_next_attempt_at;
+DROP TABLE email_outbox;
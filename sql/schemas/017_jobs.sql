@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE jobs(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    kind TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    unique_key TEXT,
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL,
+    last_error TEXT,
+    run_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    failed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_jobs_run_at ON jobs(run_at) WHERE failed_at IS NULL;
+CREATE INDEX idx_jobs_failed_at ON jobs(failed_at) WHERE failed_at IS NOT NULL;
+CREATE UNIQUE INDEX idx_jobs_unique_key ON jobs(unique_key) WHERE failed_at IS NULL;
+
+INSERT INTO permissions (name, description) VALUES
+    ('jobs:read', 'View background jobs'),
+    ('jobs:write', 'Retry background jobs');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles CROSS JOIN permissions
+WHERE roles.name = 'admin' AND permissions.name IN ('jobs:read', 'jobs:write');
+
+-- +goose Down
+DELETE FROM permissions WHERE name IN ('jobs:read', 'jobs:write');
+DROP INDEX IF EXISTS idx_jobs_unique_key;
+DROP INDEX IF EXISTS idx_jobs_failed_at;
+DROP INDEX IF EXISTS idx_jobs_run_at;
+DROP TABLE jobs;
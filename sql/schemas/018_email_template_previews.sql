@@ -0,0 +1,10 @@
+-- +goose Up
+INSERT INTO permissions (name, description) VALUES
+    ('emails:read', 'Preview email templates');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles CROSS JOIN permissions
+WHERE roles.name = 'admin' AND permissions.name = 'emails:read';
+
+-- +goose Down
+DELETE FROM permissions WHERE name = 'emails:read';
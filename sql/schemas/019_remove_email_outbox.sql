@@ -0,0 +1,35 @@
+-- +goose Up
+-- Verification emails still waiting in the outbox are handed to the job
+-- queue, which issues a fresh code when it sends them.
+INSERT INTO jobs (kind, payload, unique_key, max_attempts)
+SELECT DISTINCT ON (users.id)
+    'send_otp_email',
+    jsonb_build_object('user_id', users.id, 'type', 'email_verification', 'locale', email_outbox.locale),
+    'otp_email:' || users.id || ':email_verification',
+    10
+FROM email_outbox
+JOIN users ON users.email = email_outbox.recipient
+WHERE email_outbox.template = 'email_confirmation.tmpl'
+    AND email_outbox.dead_lettered_at IS NULL
+    AND users.verified_at IS NULL
+ORDER BY users.id, email_outbox.created_at DESC
+ON CONFLICT DO NOTHING;
+
+DROP INDEX IF EXISTS idx_email_outbox_next_attempt_at;
+DROP TABLE email_outbox;
+
+-- +goose Down
+CREATE TABLE email_outbox(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    recipient TEXT NOT NULL,
+    locale TEXT NOT NULL DEFAULT '',
+    template TEXT NOT NULL,
+    data JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    dead_lettered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_email_outbox_next_attempt_at ON email_outbox(next_attempt_at) WHERE dead_lettered_at IS NULL;